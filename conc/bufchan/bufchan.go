@@ -0,0 +1,241 @@
+// Package bufchan has channel-like buffers that a plain make(chan T, n)
+// can't give you: Unbounded never blocks its senders, and Ring keeps a fixed
+// number of values and drops the oldest or newest one when it overflows.
+//
+// Both expose an In channel to send on and an Out channel to receive from, so
+// they drop into a select like any other channel. A goroutine moves values
+// between the two; close In when you are done sending and Out is closed once
+// the values still buffered have been received.
+package bufchan
+
+import (
+	"sync/atomic"
+	"unsafe"
+)
+
+// Stats is a snapshot of a buffer's counters.
+type Stats struct {
+	Len       int    // values buffered right now
+	Cap       int    // slots in the backing array
+	Peak      int    // largest Len seen
+	Sent      uint64 // values received from In
+	Delivered uint64 // values handed out on Out
+	Dropped   uint64 // values discarded on overflow (Ring only)
+	Bytes     int64  // size of the backing array, not counting what the values point to
+}
+
+type counters struct {
+	len, cap, peak           atomic.Int64
+	sent, delivered, dropped atomic.Uint64
+	elemSize                 int64
+}
+
+func (c *counters) stats() Stats {
+	return Stats{
+		Len:       int(c.len.Load()),
+		Cap:       int(c.cap.Load()),
+		Peak:      int(c.peak.Load()),
+		Sent:      c.sent.Load(),
+		Delivered: c.delivered.Load(),
+		Dropped:   c.dropped.Load(),
+		Bytes:     c.cap.Load() * c.elemSize,
+	}
+}
+
+// observe publishes the deque's size after a change.
+func (c *counters) observe(n, capacity int) {
+	c.len.Store(int64(n))
+	c.cap.Store(int64(capacity))
+	if int64(n) > c.peak.Load() {
+		c.peak.Store(int64(n))
+	}
+}
+
+// deque is a circular buffer owned by a single goroutine.
+type deque[T any] struct {
+	buf  []T
+	head int
+	n    int
+}
+
+func (d *deque[T]) push(v T) {
+	if d.n == len(d.buf) {
+		d.resize(max(2*len(d.buf), minCap))
+	}
+	d.buf[(d.head+d.n)%len(d.buf)] = v
+	d.n++
+}
+
+func (d *deque[T]) front() T { return d.buf[d.head] }
+
+func (d *deque[T]) pop() T {
+	var zero T
+	v := d.buf[d.head]
+	d.buf[d.head] = zero // let the GC have whatever v points to
+	d.head = (d.head + 1) % len(d.buf)
+	d.n--
+	return v
+}
+
+func (d *deque[T]) resize(size int) {
+	buf := make([]T, size)
+	for i := 0; i < d.n; i++ {
+		buf[i] = d.buf[(d.head+i)%len(d.buf)]
+	}
+	d.buf, d.head = buf, 0
+}
+
+const minCap = 16
+
+// Unbounded is a FIFO channel whose senders never block: values pile up in
+// memory until someone receives them. Its backing array grows as needed and
+// shrinks again once it is mostly empty; Stats reports how big it has got.
+type Unbounded[T any] struct {
+	in  chan T
+	out chan T
+	c   counters
+}
+
+// NewUnbounded starts an empty Unbounded.
+func NewUnbounded[T any]() *Unbounded[T] {
+	var zero T
+	u := &Unbounded[T]{in: make(chan T), out: make(chan T)}
+	u.c.elemSize = int64(unsafe.Sizeof(zero))
+	go u.run()
+	return u
+}
+
+// In is where values are sent. Close it when done.
+func (u *Unbounded[T]) In() chan<- T { return u.in }
+
+// Out delivers the values in the order they were sent.
+func (u *Unbounded[T]) Out() <-chan T { return u.out }
+
+// Len reports how many values are buffered.
+func (u *Unbounded[T]) Len() int { return int(u.c.len.Load()) }
+
+// Stats returns the current counters.
+func (u *Unbounded[T]) Stats() Stats { return u.c.stats() }
+
+func (u *Unbounded[T]) run() {
+	defer close(u.out)
+	var q deque[T]
+	in := u.in
+	for in != nil || q.n > 0 {
+		var (
+			out  chan T
+			next T
+		)
+		if q.n > 0 {
+			out, next = u.out, q.front()
+		}
+		select {
+		case v, ok := <-in:
+			if !ok {
+				in = nil
+				continue
+			}
+			q.push(v)
+			u.c.sent.Add(1)
+		case out <- next:
+			q.pop()
+			u.c.delivered.Add(1)
+			if len(q.buf) > minCap && q.n < len(q.buf)/4 {
+				q.resize(len(q.buf) / 2)
+			}
+		}
+		u.c.observe(q.n, len(q.buf))
+	}
+}
+
+// Overflow says which value a full Ring gives up.
+type Overflow int
+
+const (
+	// DropOldest discards the value that has been buffered longest to make
+	// room for the new one, so receivers always see the most recent values.
+	DropOldest Overflow = iota
+	// DropNewest discards the value being sent, keeping what is buffered.
+	DropNewest
+)
+
+func (o Overflow) String() string {
+	if o == DropNewest {
+		return "drop-newest"
+	}
+	return "drop-oldest"
+}
+
+// Ring is a FIFO channel holding at most size values. Senders never block;
+// when the ring is full a value is dropped according to its Overflow policy
+// and counted in Stats.Dropped.
+type Ring[T any] struct {
+	in     chan T
+	out    chan T
+	size   int
+	policy Overflow
+	c      counters
+}
+
+// NewRing starts an empty Ring of the given size (at least 1).
+func NewRing[T any](size int, policy Overflow) *Ring[T] {
+	if size < 1 {
+		size = 1
+	}
+	var zero T
+	r := &Ring[T]{in: make(chan T), out: make(chan T), size: size, policy: policy}
+	r.c.elemSize = int64(unsafe.Sizeof(zero))
+	r.c.cap.Store(int64(size))
+	go r.run()
+	return r
+}
+
+// In is where values are sent. Close it when done.
+func (r *Ring[T]) In() chan<- T { return r.in }
+
+// Out delivers the values that survived, in the order they were sent.
+func (r *Ring[T]) Out() <-chan T { return r.out }
+
+// Len reports how many values are buffered.
+func (r *Ring[T]) Len() int { return int(r.c.len.Load()) }
+
+// Dropped reports how many values have been discarded on overflow.
+func (r *Ring[T]) Dropped() uint64 { return r.c.dropped.Load() }
+
+// Stats returns the current counters.
+func (r *Ring[T]) Stats() Stats { return r.c.stats() }
+
+func (r *Ring[T]) run() {
+	defer close(r.out)
+	q := deque[T]{buf: make([]T, r.size)}
+	in := r.in
+	for in != nil || q.n > 0 {
+		var (
+			out  chan T
+			next T
+		)
+		if q.n > 0 {
+			out, next = r.out, q.front()
+		}
+		select {
+		case v, ok := <-in:
+			if !ok {
+				in = nil
+				continue
+			}
+			r.c.sent.Add(1)
+			if q.n == r.size {
+				r.c.dropped.Add(1)
+				if r.policy == DropNewest {
+					continue
+				}
+				q.pop()
+			}
+			q.push(v)
+		case out <- next:
+			q.pop()
+			r.c.delivered.Add(1)
+		}
+		r.c.observe(q.n, len(q.buf))
+	}
+}
@@ -0,0 +1,90 @@
+package bufchan
+
+import (
+	"conc/leakcheck"
+	"fmt"
+	"testing"
+)
+
+func drain[T any](out <-chan T) []T {
+	var vs []T
+	for v := range out {
+		vs = append(vs, v)
+	}
+	return vs
+}
+
+func TestUnboundedKeepsOrderAndDrainsOnClose(t *testing.T) {
+	defer leakcheck.Take().Check(t)
+
+	const n = 1000
+	u := NewUnbounded[int]()
+	// Nobody receives yet, and the sends still don't block.
+	for i := 0; i < n; i++ {
+		u.In() <- i
+	}
+	close(u.In())
+
+	got := drain(u.Out())
+	if len(got) != n {
+		t.Fatalf("received %d values, want %d", len(got), n)
+	}
+	for i, v := range got {
+		if v != i {
+			t.Fatalf("value %d is %d; order not kept", i, v)
+		}
+	}
+	st := u.Stats()
+	if st.Sent != n || st.Delivered != n || st.Len != 0 || st.Peak < n-1 {
+		t.Errorf("stats = %+v, want %d sent and delivered, nothing left", st, n)
+	}
+	if st.Cap >= st.Peak {
+		t.Errorf("backing array stayed at %d slots after draining", st.Cap)
+	}
+}
+
+func TestRing(t *testing.T) {
+	for _, tc := range []struct {
+		policy Overflow
+		want   []int
+	}{
+		{DropOldest, []int{7, 8, 9}},
+		{DropNewest, []int{0, 1, 2}},
+	} {
+		t.Run(tc.policy.String(), func(t *testing.T) {
+			defer leakcheck.Take().Check(t)
+
+			r := NewRing[int](3, tc.policy)
+			for i := 0; i < 10; i++ {
+				r.In() <- i
+			}
+			close(r.In())
+
+			if got := drain(r.Out()); fmt.Sprint(got) != fmt.Sprint(tc.want) {
+				t.Errorf("received %v, want %v", got, tc.want)
+			}
+			if st := r.Stats(); r.Dropped() != 7 || st.Sent != 10 || st.Delivered != 3 || st.Peak != 3 {
+				t.Errorf("stats = %+v, want 10 sent, 7 dropped, 3 delivered", st)
+			}
+		})
+	}
+}
+
+func TestRingDeliversWhileSending(t *testing.T) {
+	defer leakcheck.Take().Check(t)
+
+	r := NewRing[int](2, DropOldest)
+	r.In() <- 1
+	if v := <-r.Out(); v != 1 {
+		t.Fatalf("received %d, want 1", v)
+	}
+	r.In() <- 2
+	r.In() <- 3
+	close(r.In())
+	if got := drain(r.Out()); fmt.Sprint(got) != "[2 3]" {
+		t.Errorf("received %v, want [2 3]", got)
+	}
+	if r.Dropped() != 0 {
+		t.Errorf("dropped %d values without overflowing", r.Dropped())
+	}
+}
@@ -0,0 +1,178 @@
+// Package chanx has generic combinators for building channel pipelines.
+//
+// Every combinator takes a ctx: when it is done, the combinator stops
+// reading, closes its outputs and its goroutine exits, even if nobody is
+// reading its outputs any more. Outputs are also closed when the inputs are
+// exhausted. Combinators that stop early (Take) leave the rest of their input
+// unread, so the producer feeding it should watch the same ctx.
+package chanx
+
+import (
+	"context"
+	"sync"
+)
+
+// OrDone relays in until it is closed or ctx is done, so callers can range
+// over a channel without writing the select themselves.
+func OrDone[T any](ctx context.Context, in <-chan T) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				select {
+				case out <- v:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Merge fans any number of channels into one, closed after all of them are.
+func Merge[T any](ctx context.Context, ins ...<-chan T) <-chan T {
+	out := make(chan T)
+	wg := new(sync.WaitGroup)
+	wg.Add(len(ins))
+	for _, in := range ins {
+		go func(in <-chan T) {
+			defer wg.Done()
+			for v := range OrDone(ctx, in) {
+				select {
+				case out <- v:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}(in)
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+// Tee copies every value of in to both outputs. The next value is only read
+// once both outputs have taken the current one, so a slow reader on either
+// side slows both down.
+func Tee[T any](ctx context.Context, in <-chan T) (<-chan T, <-chan T) {
+	out1, out2 := make(chan T), make(chan T)
+	go func() {
+		defer close(out1)
+		defer close(out2)
+		for v := range OrDone(ctx, in) {
+			// Shadow with local copies and nil each out once it's been served.
+			o1, o2 := out1, out2
+			for i := 0; i < 2; i++ {
+				select {
+				case o1 <- v:
+					o1 = nil
+				case o2 <- v:
+					o2 = nil
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out1, out2
+}
+
+// Bridge flattens a channel of channels, reading each inner channel to the
+// end before moving on to the next.
+func Bridge[T any](ctx context.Context, chans <-chan (<-chan T)) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for in := range OrDone(ctx, chans) {
+			for v := range OrDone(ctx, in) {
+				select {
+				case out <- v:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// Take relays the first n values of in, then closes.
+func Take[T any](ctx context.Context, in <-chan T, n int) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for i := 0; i < n; i++ {
+			select {
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				select {
+				case out <- v:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Skip discards the first n values of in and relays the rest.
+func Skip[T any](ctx context.Context, in <-chan T, n int) <-chan T {
+	skipped := 0
+	return Filter(ctx, in, func(T) bool {
+		if skipped < n {
+			skipped++
+			return false
+		}
+		return true
+	})
+}
+
+// Filter relays the values of in for which keep returns true.
+func Filter[T any](ctx context.Context, in <-chan T, keep func(T) bool) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for v := range OrDone(ctx, in) {
+			if !keep(v) {
+				continue
+			}
+			select {
+			case out <- v:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Map relays fn(v) for every value v of in.
+func Map[T, U any](ctx context.Context, in <-chan T, fn func(T) U) <-chan U {
+	out := make(chan U)
+	go func() {
+		defer close(out)
+		for v := range OrDone(ctx, in) {
+			select {
+			case out <- fn(v):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
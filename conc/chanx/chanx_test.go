@@ -0,0 +1,142 @@
+package chanx
+
+import (
+	"conc/leakcheck"
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// gen sends vs on a new channel and closes it, or gives up when ctx is done.
+func gen(ctx context.Context, vs ...int) <-chan int {
+	out := make(chan int)
+	go func() {
+		defer close(out)
+		for _, v := range vs {
+			select {
+			case out <- v:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+func collect(ch <-chan int) []int {
+	var vs []int
+	for v := range ch {
+		vs = append(vs, v)
+	}
+	return vs
+}
+
+func TestMerge(t *testing.T) {
+	defer leakcheck.Take().Check(t)
+
+	ctx := context.Background()
+	got := collect(Merge(ctx, gen(ctx, 1, 2), gen(ctx, 3), gen(ctx)))
+	sort.Ints(got)
+	if want := []int{1, 2, 3}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestTee(t *testing.T) {
+	defer leakcheck.Take().Check(t)
+
+	ctx := context.Background()
+	a, b := Tee(ctx, gen(ctx, 1, 2, 3))
+	gotB := make(chan []int)
+	go func() { gotB <- collect(b) }()
+	want := []int{1, 2, 3}
+	if got := collect(a); !reflect.DeepEqual(got, want) {
+		t.Fatalf("first output %v, want %v", got, want)
+	}
+	if got := <-gotB; !reflect.DeepEqual(got, want) {
+		t.Fatalf("second output %v, want %v", got, want)
+	}
+}
+
+func TestBridge(t *testing.T) {
+	defer leakcheck.Take().Check(t)
+
+	ctx := context.Background()
+	chans := make(chan (<-chan int), 3)
+	chans <- gen(ctx, 1, 2)
+	chans <- gen(ctx)
+	chans <- gen(ctx, 3)
+	close(chans)
+	if got, want := collect(Bridge(ctx, chans)), []int{1, 2, 3}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestTakeAndSkip(t *testing.T) {
+	defer leakcheck.Take().Check(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if got, want := collect(Take(ctx, gen(ctx, 1, 2, 3, 4), 2)), []int{1, 2}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Take: got %v, want %v", got, want)
+	}
+	if got, want := collect(Take(ctx, gen(ctx, 1), 5)), []int{1}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Take past the end: got %v, want %v", got, want)
+	}
+	if got, want := collect(Skip(ctx, gen(ctx, 1, 2, 3, 4), 3)), []int{4}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Skip: got %v, want %v", got, want)
+	}
+	cancel() // releases the generator Take left with values unsent
+}
+
+// TestCancelClosesOutputs reads one value from each combinator, stops reading
+// and cancels: every output must close and no goroutine may be left behind,
+// even though the inputs still have values and nobody drains the outputs.
+func TestCancelClosesOutputs(t *testing.T) {
+	defer leakcheck.Take().Check(t)
+
+	endless := func(ctx context.Context) <-chan int {
+		out := make(chan int)
+		go func() {
+			defer close(out)
+			for i := 0; ; i++ {
+				select {
+				case out <- i:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+		return out
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	teeA, teeB := Tee(ctx, endless(ctx))
+	chans := make(chan (<-chan int), 1)
+	chans <- endless(ctx)
+	outs := map[string]<-chan int{
+		"Merge":  Merge(ctx, endless(ctx), endless(ctx)),
+		"Tee":    teeA,
+		"Bridge": Bridge(ctx, chans),
+		"Take":   Take(ctx, endless(ctx), 100),
+		"Skip":   Skip(ctx, endless(ctx), 3),
+		"Filter": Filter(ctx, endless(ctx), func(v int) bool { return v%2 == 0 }),
+		"Map":    Map(ctx, endless(ctx), func(v int) int { return v * 2 }),
+	}
+	go func() {
+		for range teeB {
+		}
+	}()
+	for name, out := range outs {
+		if _, ok := <-out; !ok {
+			t.Fatalf("%s closed before cancel", name)
+		}
+	}
+	cancel()
+	// A value already in flight may still arrive; each channel must close
+	// after it.
+	for _, out := range outs {
+		for range out {
+		}
+	}
+}
@@ -0,0 +1,372 @@
+package dqueue
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrClosed is returned by every method once Close has been called.
+	ErrClosed = errors.New("dqueue: queue closed")
+	// ErrUnknown is returned by Ack and Nack for a message that isn't in flight.
+	ErrUnknown = errors.New("dqueue: message not in flight")
+)
+
+// Message is one job handed out by Receive. Attempts counts deliveries,
+// including this one and any made before a crash.
+type Message struct {
+	ID       uint64
+	Body     []byte
+	Attempts int
+}
+
+// record is one line of the log.
+type record struct {
+	Op   string `json:"op"` // put, recv, ack or nack
+	ID   uint64 `json:"id"`
+	Body []byte `json:"body,omitempty"`
+}
+
+type message struct {
+	Message
+	inflight bool
+	visible  time.Time // when an in-flight message is handed out again
+}
+
+// Queue is a crash-safe FIFO job queue backed by an append-only JSON-lines log.
+//
+// Every Put, Receive, Ack and Nack is written (and by default fsynced) before
+// it takes effect, so after a crash Open replays the log and everything that
+// was never acked is delivered again: processing is at-least-once, and
+// handlers should be idempotent.
+//
+// A received message must be acked within the visibility timeout; otherwise it
+// is assumed lost with its worker and becomes receivable again.
+type Queue struct {
+	path       string
+	visibility time.Duration
+	noSync     bool
+
+	mu     sync.Mutex
+	f      *os.File
+	w      *bufio.Writer
+	msgs   map[uint64]*message
+	ready  []uint64 // FIFO of receivable ids
+	nextID uint64
+	closed bool
+	wake   chan struct{} // closed and replaced whenever a message becomes ready
+}
+
+// Option configures a Queue.
+type Option func(*Queue)
+
+// WithVisibilityTimeout sets how long a received message may go unacked
+// before it is redelivered (default 30s).
+func WithVisibilityTimeout(d time.Duration) Option {
+	return func(q *Queue) { q.visibility = d }
+}
+
+// WithoutSync skips fsync after each write. Faster, but a machine crash (not
+// just a process crash) can lose the last few operations.
+func WithoutSync() Option {
+	return func(q *Queue) { q.noSync = true }
+}
+
+// Open opens or creates the queue log at path, replays it, and compacts it so
+// that it only holds unacked messages.
+func Open(path string, opts ...Option) (*Queue, error) {
+	q := &Queue{
+		path:       path,
+		visibility: 30 * time.Second,
+		msgs:       map[uint64]*message{},
+		wake:       make(chan struct{}),
+		nextID:     1,
+	}
+	for _, opt := range opts {
+		opt(q)
+	}
+
+	if err := q.replay(); err != nil {
+		return nil, err
+	}
+	if err := q.compact(); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// replay rebuilds state from the log. Anything that was in flight when the
+// previous process died is ready again. A torn final line, left by a crash in
+// the middle of a write, is ignored.
+func (q *Queue) replay() error {
+	data, err := os.ReadFile(q.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	lines := bytes.Split(data, []byte("\n"))
+	for i, line := range lines {
+		if len(line) == 0 {
+			continue
+		}
+		var rec record
+		if err := json.Unmarshal(line, &rec); err != nil {
+			if i == len(lines)-1 {
+				break
+			}
+			return fmt.Errorf("dqueue: %s line %d: %w", q.path, i+1, err)
+		}
+		switch rec.Op {
+		case "put":
+			q.msgs[rec.ID] = &message{Message: Message{ID: rec.ID, Body: rec.Body}}
+			if rec.ID >= q.nextID {
+				q.nextID = rec.ID + 1
+			}
+		case "recv":
+			if m, ok := q.msgs[rec.ID]; ok {
+				m.Attempts++
+			}
+		case "ack":
+			delete(q.msgs, rec.ID)
+		}
+	}
+
+	// Ids are handed out in increasing order, so sorting restores FIFO.
+	for id := range q.msgs {
+		q.ready = append(q.ready, id)
+	}
+	sortIDs(q.ready)
+	return nil
+}
+
+// compact rewrites the log with only the live messages and switches to it.
+func (q *Queue) compact() error {
+	tmp := q.path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, id := range q.ready {
+		m := q.msgs[id]
+		if err := enc.Encode(record{Op: "put", ID: id, Body: m.Body}); err != nil {
+			f.Close()
+			return err
+		}
+		for i := 0; i < m.Attempts; i++ {
+			if err := enc.Encode(record{Op: "recv", ID: id}); err != nil {
+				f.Close()
+				return err
+			}
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, q.path); err != nil {
+		return err
+	}
+
+	q.f, err = os.OpenFile(q.path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	q.w = bufio.NewWriter(q.f)
+	return nil
+}
+
+// write appends rec to the log and makes it durable. Callers hold q.mu.
+func (q *Queue) write(rec record) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	if _, err := q.w.Write(line); err != nil {
+		return err
+	}
+	if err := q.w.Flush(); err != nil {
+		return err
+	}
+	if q.noSync {
+		return nil
+	}
+	return q.f.Sync()
+}
+
+// Put durably appends a job and returns its id.
+func (q *Queue) Put(body []byte) (uint64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return 0, ErrClosed
+	}
+
+	id := q.nextID
+	if err := q.write(record{Op: "put", ID: id, Body: body}); err != nil {
+		return 0, err
+	}
+	q.nextID++
+	q.msgs[id] = &message{Message: Message{ID: id, Body: body}}
+	q.ready = append(q.ready, id)
+	q.broadcast()
+	return id, nil
+}
+
+// Receive blocks until a message is available, marks it in flight and returns it.
+func (q *Queue) Receive(ctx context.Context) (Message, error) {
+	for {
+		q.mu.Lock()
+		if q.closed {
+			q.mu.Unlock()
+			return Message{}, ErrClosed
+		}
+		next := q.requeueExpired()
+
+		if len(q.ready) > 0 {
+			m := q.msgs[q.ready[0]]
+			if err := q.write(record{Op: "recv", ID: m.ID}); err != nil {
+				q.mu.Unlock()
+				return Message{}, err
+			}
+			q.ready = q.ready[1:]
+			m.Attempts++
+			m.inflight = true
+			m.visible = time.Now().Add(q.visibility)
+			q.mu.Unlock()
+			return m.Message, nil
+		}
+
+		wake := q.wake
+		q.mu.Unlock()
+
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		if !next.IsZero() {
+			timer = time.NewTimer(time.Until(next))
+			timeout = timer.C
+		}
+		select {
+		case <-wake:
+		case <-timeout:
+		case <-ctx.Done():
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if ctx.Err() != nil {
+			return Message{}, ctx.Err()
+		}
+	}
+}
+
+// requeueExpired makes in-flight messages past their visibility timeout ready
+// again and returns the earliest remaining timeout (zero if none). Callers hold q.mu.
+func (q *Queue) requeueExpired() time.Time {
+	now := time.Now()
+	var (
+		next    time.Time
+		expired []uint64
+	)
+	for id, m := range q.msgs {
+		if !m.inflight {
+			continue
+		}
+		if !now.Before(m.visible) {
+			m.inflight = false
+			expired = append(expired, id)
+			continue
+		}
+		if next.IsZero() || m.visible.Before(next) {
+			next = m.visible
+		}
+	}
+	sortIDs(expired)
+	q.ready = append(q.ready, expired...)
+	return next
+}
+
+// Ack marks an in-flight message as done; it will never be delivered again.
+func (q *Queue) Ack(id uint64) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrClosed
+	}
+	if m, ok := q.msgs[id]; !ok || !m.inflight {
+		return ErrUnknown
+	}
+	if err := q.write(record{Op: "ack", ID: id}); err != nil {
+		return err
+	}
+	delete(q.msgs, id)
+	return nil
+}
+
+// Nack returns an in-flight message to the back of the queue straight away.
+func (q *Queue) Nack(id uint64) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrClosed
+	}
+	m, ok := q.msgs[id]
+	if !ok || !m.inflight {
+		return ErrUnknown
+	}
+	if err := q.write(record{Op: "nack", ID: id}); err != nil {
+		return err
+	}
+	m.inflight = false
+	q.ready = append(q.ready, id)
+	q.broadcast()
+	return nil
+}
+
+// Pending reports how many messages have not been acked yet, in flight or not.
+func (q *Queue) Pending() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.msgs)
+}
+
+// Close flushes and closes the log. Unacked messages stay in it for the next Open.
+func (q *Queue) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return nil
+	}
+	q.closed = true
+	q.broadcast()
+	return errors.Join(q.w.Flush(), q.f.Close())
+}
+
+func (q *Queue) broadcast() {
+	close(q.wake)
+	q.wake = make(chan struct{})
+}
+
+func sortIDs(ids []uint64) {
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+}
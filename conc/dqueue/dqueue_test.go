@@ -0,0 +1,186 @@
+package dqueue
+
+import (
+	"conc/leakcheck"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// crashEnv, when set to a queue path, makes the test binary act as the worker
+// process that TestCrashRedelivers kills.
+const crashEnv = "DQUEUE_CRASH_CHILD"
+
+func TestMain(m *testing.M) {
+	if path := os.Getenv(crashEnv); path != "" {
+		crashChild(path)
+	}
+	os.Exit(m.Run())
+}
+
+// crashChild receives five messages, acks the first three and dies holding
+// the other two, in the middle of writing one more record.
+func crashChild(path string) {
+	q, err := Open(path)
+	if err != nil {
+		os.Exit(3)
+	}
+	for i := 0; i < 5; i++ {
+		m, err := q.Receive(context.Background())
+		if err != nil {
+			os.Exit(3)
+		}
+		if i < 3 && q.Ack(m.ID) != nil {
+			os.Exit(3)
+		}
+	}
+	// A torn final line, as if the process died part-way through a write.
+	q.f.WriteString(`{"op":"ack","id":`)
+	os.Exit(2)
+}
+
+// TestCrashRedelivers kills a process part-way through the queue and checks
+// that reopening it hands out everything that wasn't acked, and nothing that was.
+func TestCrashRedelivers(t *testing.T) {
+	defer leakcheck.Take().Check(t)
+
+	path := filepath.Join(t.TempDir(), "q.log")
+	q, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 10; i++ {
+		if _, err := q.Put([]byte(strconv.Itoa(i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := q.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	child := exec.Command(os.Args[0], "-test.run=^$")
+	child.Env = append(os.Environ(), crashEnv+"="+path)
+	var exit *exec.ExitError
+	if err := child.Run(); !errors.As(err, &exit) || exit.ExitCode() != 2 {
+		t.Fatalf("child = %v, want exit status 2", err)
+	}
+
+	q, err = Open(path, WithoutSync())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	if n := q.Pending(); n != 7 {
+		t.Fatalf("Pending after crash = %d, want 7", n)
+	}
+	for want := 4; want <= 10; want++ {
+		m, err := q.Receive(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(m.Body) != strconv.Itoa(want) {
+			t.Fatalf("got message %s, want %d", m.Body, want)
+		}
+		// 4 and 5 were in flight when the child died.
+		attempts := 1
+		if want <= 5 {
+			attempts = 2
+		}
+		if m.Attempts != attempts {
+			t.Errorf("message %s: Attempts = %d, want %d", m.Body, m.Attempts, attempts)
+		}
+		if err := q.Ack(m.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := q.Pending(); n != 0 {
+		t.Fatalf("Pending = %d after acking everything", n)
+	}
+}
+
+func TestReceiveWaitsForPut(t *testing.T) {
+	defer leakcheck.Take().Check(t)
+
+	q, err := Open(filepath.Join(t.TempDir(), "q.log"), WithoutSync())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+
+	got := make(chan Message, 1)
+	go func() {
+		m, err := q.Receive(context.Background())
+		if err != nil {
+			t.Error(err)
+		}
+		got <- m
+	}()
+	time.Sleep(10 * time.Millisecond)
+	if _, err := q.Put([]byte("job")); err != nil {
+		t.Fatal(err)
+	}
+	m := <-got
+	if string(m.Body) != "job" || m.Attempts != 1 {
+		t.Fatalf("got %+v", m)
+	}
+	if err := q.Ack(m.ID); err != nil {
+		t.Fatal(err)
+	}
+	if n := q.Pending(); n != 0 {
+		t.Fatalf("Pending = %d after Ack", n)
+	}
+}
+
+func TestReceiveReturnsOnCloseAndCancel(t *testing.T) {
+	defer leakcheck.Take().Check(t)
+
+	q, err := Open(filepath.Join(t.TempDir(), "q.log"), WithoutSync())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := q.Receive(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Receive = %v, want DeadlineExceeded", err)
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := q.Receive(context.Background())
+		errc <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	q.Close()
+	if err := <-errc; !errors.Is(err, ErrClosed) {
+		t.Fatalf("Receive after Close = %v, want ErrClosed", err)
+	}
+}
+
+func TestVisibilityTimeoutRedelivers(t *testing.T) {
+	defer leakcheck.Take().Check(t)
+
+	q, err := Open(filepath.Join(t.TempDir(), "q.log"), WithoutSync(), WithVisibilityTimeout(20*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	if _, err := q.Put([]byte("job")); err != nil {
+		t.Fatal(err)
+	}
+	first, err := q.Receive(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := q.Receive(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.ID != first.ID || again.Attempts != 2 {
+		t.Fatalf("redelivery = %+v, want id %d attempt 2", again, first.ID)
+	}
+}
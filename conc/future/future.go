@@ -0,0 +1,223 @@
+// Package future runs a computation in a goroutine and hands back a typed
+// handle to its result, instead of a shared variable or a one-shot channel.
+package future
+
+import (
+	"conc/safego"
+	"context"
+	"errors"
+)
+
+// PanicError is the error a Future settles with when its function panics.
+type PanicError = safego.PanicError
+
+// Waiter is the untyped view of a Future, so futures of different types can
+// be awaited together with Wait.
+type Waiter interface {
+	Done() <-chan struct{}
+	Err() error
+}
+
+// Future is the eventual result of a computation. It settles exactly once.
+type Future[T any] struct {
+	done chan struct{}
+	val  T
+	err  error
+}
+
+func newFuture[T any]() *Future[T] {
+	return &Future[T]{done: make(chan struct{})}
+}
+
+func (f *Future[T]) settle(v T, err error) {
+	f.val, f.err = v, err
+	close(f.done)
+}
+
+// Go runs fn in a new goroutine. A panic in fn settles the future with a *PanicError.
+func Go[T any](ctx context.Context, fn func(context.Context) (T, error)) *Future[T] {
+	f := newFuture[T]()
+	go func() {
+		var v T
+		err := safego.Call("future", func() error {
+			var err error
+			v, err = fn(ctx)
+			return err
+		})
+		if _, ok := err.(*PanicError); ok {
+			var zero T
+			v = zero
+		}
+		f.settle(v, err)
+	}()
+	return f
+}
+
+// Resolved returns a future already settled with v.
+func Resolved[T any](v T) *Future[T] {
+	f := newFuture[T]()
+	f.settle(v, nil)
+	return f
+}
+
+// Failed returns a future already settled with err.
+func Failed[T any](err error) *Future[T] {
+	f := newFuture[T]()
+	var zero T
+	f.settle(zero, err)
+	return f
+}
+
+// Done is closed once the future has settled.
+func (f *Future[T]) Done() <-chan struct{} {
+	return f.done
+}
+
+// Err returns the future's error once settled, and nil before that.
+func (f *Future[T]) Err() error {
+	select {
+	case <-f.done:
+		return f.err
+	default:
+		return nil
+	}
+}
+
+// Await blocks until the future settles or ctx is done. Giving up on ctx
+// doesn't stop the computation; the future still settles later.
+func (f *Future[T]) Await(ctx context.Context) (T, error) {
+	select {
+	case <-f.done:
+		return f.val, f.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// Then runs fn on f's value once it succeeds. If f fails, the new future
+// fails with the same error and fn is not called.
+func Then[T, U any](ctx context.Context, f *Future[T], fn func(context.Context, T) (U, error)) *Future[U] {
+	return Go(ctx, func(ctx context.Context) (U, error) {
+		v, err := f.Await(ctx)
+		if err != nil {
+			var zero U
+			return zero, err
+		}
+		return fn(ctx, v)
+	})
+}
+
+// All settles with every value, in argument order, once all futures succeed,
+// or with the first error as soon as any of them fails.
+func All[T any](ctx context.Context, fs ...*Future[T]) *Future[[]T] {
+	return Go(ctx, func(ctx context.Context) ([]T, error) {
+		if err := Wait(ctx, waiters(fs)...); err != nil {
+			return nil, err
+		}
+		vals := make([]T, len(fs))
+		for i, f := range fs {
+			vals[i] = f.val
+		}
+		return vals, nil
+	})
+}
+
+// Any settles with the first successful value. If every future fails it
+// settles with all their errors joined.
+func Any[T any](ctx context.Context, fs ...*Future[T]) *Future[T] {
+	return Go(ctx, func(ctx context.Context) (T, error) {
+		var (
+			zero T
+			errs []error
+		)
+		pending := append([]*Future[T](nil), fs...)
+		for len(pending) > 0 {
+			i, err := first(ctx, waiters(pending))
+			if err != nil {
+				return zero, err
+			}
+			if f := pending[i]; f.err == nil {
+				return f.val, nil
+			}
+			errs = append(errs, pending[i].err)
+			pending = append(pending[:i], pending[i+1:]...)
+		}
+		if len(errs) == 0 {
+			return zero, errors.New("future: Any of no futures")
+		}
+		return zero, errors.Join(errs...)
+	})
+}
+
+// Race settles like whichever future settles first, success or failure.
+func Race[T any](ctx context.Context, fs ...*Future[T]) *Future[T] {
+	return Go(ctx, func(ctx context.Context) (T, error) {
+		var zero T
+		if len(fs) == 0 {
+			return zero, errors.New("future: Race of no futures")
+		}
+		i, err := first(ctx, waiters(fs))
+		if err != nil {
+			return zero, err
+		}
+		return fs[i].val, fs[i].err
+	})
+}
+
+// Wait blocks until every waiter has settled, returning early with the first
+// error any of them settles with, or ctx's error. The futures may have
+// different types; read each one's value with Await afterwards.
+func Wait(ctx context.Context, ws ...Waiter) error {
+	pending := append([]Waiter(nil), ws...)
+	for len(pending) > 0 {
+		i, err := first(ctx, pending)
+		if err != nil {
+			return err
+		}
+		if err := pending[i].Err(); err != nil {
+			return err
+		}
+		pending = append(pending[:i], pending[i+1:]...)
+	}
+	return nil
+}
+
+// first returns the index of the first waiter to settle. It starts a watcher
+// goroutine per waiter; they all exit once it returns.
+func first(ctx context.Context, ws []Waiter) (int, error) {
+	for i, w := range ws {
+		select {
+		case <-w.Done():
+			return i, nil
+		default:
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	settled := make(chan int, len(ws))
+	for i, w := range ws {
+		go func(i int, w Waiter) {
+			select {
+			case <-w.Done():
+				settled <- i
+			case <-ctx.Done():
+			}
+		}(i, w)
+	}
+	select {
+	case i := <-settled:
+		return i, nil
+	case <-ctx.Done():
+		return -1, ctx.Err()
+	}
+}
+
+func waiters[T any](fs []*Future[T]) []Waiter {
+	ws := make([]Waiter, len(fs))
+	for i, f := range fs {
+		ws[i] = f
+	}
+	return ws
+}
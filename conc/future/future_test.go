@@ -0,0 +1,151 @@
+package future
+
+import (
+	"conc/leakcheck"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+var errBoom = errors.New("boom")
+
+// gate returns a future that settles with v once release is closed.
+func gate[T any](release <-chan struct{}, v T, err error) *Future[T] {
+	return Go(context.Background(), func(context.Context) (T, error) {
+		<-release
+		return v, err
+	})
+}
+
+func TestGoSettles(t *testing.T) {
+	defer leakcheck.Take().Check(t)
+	ctx := context.Background()
+
+	release := make(chan struct{})
+	f := gate(release, 42, nil)
+	if f.Err() != nil {
+		t.Fatal("Err is set before the future settled")
+	}
+	close(release)
+	if v, err := f.Await(ctx); v != 42 || err != nil {
+		t.Fatalf("Await = %d, %v; want 42, nil", v, err)
+	}
+
+	p := Go(ctx, func(context.Context) (int, error) { panic("oops") })
+	<-p.Done()
+	var pe *PanicError
+	if v, err := p.Await(ctx); v != 0 || !errors.As(err, &pe) {
+		t.Fatalf("Await after panic = %d, %v; want 0 and a *PanicError", v, err)
+	}
+}
+
+func TestThen(t *testing.T) {
+	defer leakcheck.Take().Check(t)
+	ctx := context.Background()
+
+	s := Then(ctx, Resolved(21), func(_ context.Context, n int) (string, error) {
+		return fmt.Sprint(2 * n), nil
+	})
+	if v, err := s.Await(ctx); v != "42" || err != nil {
+		t.Fatalf("Then = %q, %v; want \"42\", nil", v, err)
+	}
+
+	called := false
+	s = Then(ctx, Failed[int](errBoom), func(context.Context, int) (string, error) {
+		called = true
+		return "", nil
+	})
+	if _, err := s.Await(ctx); !errors.Is(err, errBoom) || called {
+		t.Fatalf("Then on a failed future = %v (fn called: %v), want errBoom without calling fn", err, called)
+	}
+}
+
+func TestAll(t *testing.T) {
+	defer leakcheck.Take().Check(t)
+	ctx := context.Background()
+
+	release := make(chan struct{})
+	all := All(ctx, Resolved(3), gate(release, 1, nil), Resolved(2))
+	select {
+	case <-all.Done():
+		t.Fatal("All settled before every future did")
+	case <-time.After(10 * time.Millisecond):
+	}
+	close(release)
+	if vals, err := all.Await(ctx); err != nil || fmt.Sprint(vals) != "[3 1 2]" {
+		t.Fatalf("All = %v, %v; want [3 1 2] in argument order", vals, err)
+	}
+
+	// The first failure settles All without waiting for the rest.
+	release = make(chan struct{})
+	defer close(release)
+	if _, err := All(ctx, gate(release, 1, nil), Failed[int](errBoom)).Await(ctx); !errors.Is(err, errBoom) {
+		t.Fatalf("All with a failure = %v, want errBoom", err)
+	}
+}
+
+func TestAny(t *testing.T) {
+	defer leakcheck.Take().Check(t)
+	ctx := context.Background()
+
+	release := make(chan struct{})
+	defer close(release)
+	if v, err := Any(ctx, Failed[int](errBoom), gate(release, 1, nil), Resolved(7)).Await(ctx); v != 7 || err != nil {
+		t.Fatalf("Any = %d, %v; want the first success, 7", v, err)
+	}
+
+	errOther := errors.New("other")
+	_, err := Any(ctx, Failed[int](errBoom), Failed[int](errOther)).Await(ctx)
+	if !errors.Is(err, errBoom) || !errors.Is(err, errOther) {
+		t.Fatalf("Any of failures = %v, want both errors joined", err)
+	}
+}
+
+func TestRace(t *testing.T) {
+	defer leakcheck.Take().Check(t)
+	ctx := context.Background()
+
+	release := make(chan struct{})
+	defer close(release)
+	if _, err := Race(ctx, gate(release, 1, nil), Failed[int](errBoom)).Await(ctx); !errors.Is(err, errBoom) {
+		t.Fatalf("Race = %v, want the failure that settled first", err)
+	}
+}
+
+func TestCancellation(t *testing.T) {
+	defer leakcheck.Take().Check(t)
+
+	// Giving up on Await leaves the computation running; it settles later.
+	release := make(chan struct{})
+	f := gate(release, 1, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := f.Await(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Await = %v, want context.DeadlineExceeded", err)
+	}
+	close(release)
+	if v, err := f.Await(context.Background()); v != 1 || err != nil {
+		t.Fatalf("Await after release = %d, %v; want 1, nil", v, err)
+	}
+
+	// Cancelling the context handed to Go reaches the function, and the
+	// combinators give up on their pending futures.
+	ctx, cancel = context.WithCancel(context.Background())
+	f = Go(ctx, func(ctx context.Context) (int, error) {
+		<-ctx.Done()
+		return 0, ctx.Err()
+	})
+	never := make(chan struct{})
+	defer close(never)
+	all := All(ctx, f, gate(never, 2, nil))
+	anyOf := Any(ctx, gate(never, 3, nil))
+	cancel()
+	for _, w := range []Waiter{f, all, anyOf} {
+		<-w.Done()
+		if !errors.Is(w.Err(), context.Canceled) {
+			t.Errorf("settled with %v, want context.Canceled", w.Err())
+		}
+	}
+}
@@ -0,0 +1,229 @@
+// Package leakcheck finds goroutines that outlive the code that started them.
+//
+// Take a snapshot before the code under test, then ask it which goroutines
+// have appeared since and are still running. Goroutines get a grace period to
+// finish on their own, since many exit shortly after the function that
+// spawned them returns.
+//
+// In a test:
+//
+//	defer leakcheck.Take().Check(t)
+//
+// In a program, Leaks returns the same information for printing.
+package leakcheck
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TB is the part of testing.TB that Check needs, so this package doesn't
+// import testing and can be driven by anything with the same methods.
+type TB interface {
+	Helper()
+	Errorf(format string, args ...any)
+}
+
+// Goroutine is one goroutine from a stack dump.
+type Goroutine struct {
+	ID    int
+	State string // e.g. "chan send", "select", "IO wait, 2 minutes"
+	Stack string // the goroutine's full trace, header line included
+}
+
+// Top returns the function the goroutine is currently in.
+func (g Goroutine) Top() string {
+	fns := functions(g.Stack)
+	if len(fns) == 0 {
+		return ""
+	}
+	return fns[0]
+}
+
+func (g Goroutine) String() string { return g.Stack }
+
+// defaultIgnore lists goroutines the runtime and standard library start on
+// their own, which come and go independently of the code being checked.
+var defaultIgnore = []string{
+	"testing.RunTests",
+	"testing.(*T).Run",
+	"testing.(*T).Parallel",
+	"testing.runTests",
+	"testing.tRunner.func1",
+	"testing.(*M).",
+	"runtime.ensureSigM",
+	"runtime/trace.Start",
+	"os/signal.signal_recv",
+	"os/signal.loop",
+	// idle keep-alive connections in net/http's pool
+	"net/http.(*persistConn).readLoop",
+	"net/http.(*persistConn).writeLoop",
+}
+
+type config struct {
+	timeout time.Duration
+	ignore  []string
+}
+
+// Option configures a check.
+type Option func(*config)
+
+// WithTimeout sets how long new goroutines get to exit before they count as
+// leaked (default 1s).
+func WithTimeout(d time.Duration) Option {
+	return func(c *config) { c.timeout = d }
+}
+
+// Ignore skips goroutines with fn anywhere in their stack. fn is matched as a
+// prefix of the function name, e.g. "main.worker" or "net/http.".
+func Ignore(fn ...string) Option {
+	return func(c *config) { c.ignore = append(c.ignore, fn...) }
+}
+
+// Snapshot is the set of goroutines that were running at some point.
+type Snapshot struct {
+	ids map[int]bool
+}
+
+// Take records the goroutines running now.
+func Take() *Snapshot {
+	s := &Snapshot{ids: make(map[int]bool)}
+	for _, g := range All() {
+		s.ids[g.ID] = true
+	}
+	return s
+}
+
+// Leaks waits up to the timeout for goroutines started since the snapshot to
+// exit and returns those that are still running. The calling goroutine and
+// the ignore list (built-in plus Ignore options) are left out.
+func (s *Snapshot) Leaks(opts ...Option) []Goroutine {
+	cfg := config{timeout: time.Second, ignore: append([]string(nil), defaultIgnore...)}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	self := currentID()
+
+	deadline := time.Now().Add(cfg.timeout)
+	wait := time.Millisecond
+	for {
+		var leaked []Goroutine
+		for _, g := range All() {
+			if g.ID == self || s.ids[g.ID] || ignored(g, cfg.ignore) {
+				continue
+			}
+			leaked = append(leaked, g)
+		}
+		if len(leaked) == 0 || time.Now().After(deadline) {
+			return leaked
+		}
+		time.Sleep(wait)
+		if wait < 100*time.Millisecond {
+			wait *= 2
+		}
+	}
+}
+
+// Check is Leaks for tests: it fails t with the stacks of any leaked
+// goroutines.
+func (s *Snapshot) Check(t TB, opts ...Option) {
+	t.Helper()
+	if leaked := s.Leaks(opts...); len(leaked) > 0 {
+		t.Errorf("%s", Report(leaked))
+	}
+}
+
+// Report formats leaked goroutines for a log or test failure.
+func Report(leaked []Goroutine) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "leakcheck: %d goroutine(s) leaked:\n", len(leaked))
+	for _, g := range leaked {
+		b.WriteString("\n")
+		b.WriteString(g.Stack)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// All returns every goroutine currently running.
+func All() []Goroutine {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+	var gs []Goroutine
+	for _, block := range bytes.Split(buf, []byte("\n\n")) {
+		if g, ok := parse(string(block)); ok {
+			gs = append(gs, g)
+		}
+	}
+	return gs
+}
+
+// parse reads one block of a runtime.Stack dump, which starts with a header
+// like "goroutine 7 [chan send, 2 minutes]:".
+func parse(block string) (Goroutine, bool) {
+	header, _, _ := strings.Cut(block, "\n")
+	rest, ok := strings.CutPrefix(header, "goroutine ")
+	if !ok {
+		return Goroutine{}, false
+	}
+	idStr, state, ok := strings.Cut(rest, " ")
+	if !ok {
+		return Goroutine{}, false
+	}
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		return Goroutine{}, false
+	}
+	state = strings.TrimSuffix(strings.TrimPrefix(state, "["), "]:")
+	return Goroutine{ID: id, State: state, Stack: block}, true
+}
+
+// functions lists the function names in a stack trace, innermost first,
+// including the "created by" one.
+func functions(stack string) []string {
+	var fns []string
+	lines := strings.Split(stack, "\n")
+	for _, line := range lines[1:] {
+		if line == "" || strings.HasPrefix(line, "\t") {
+			continue // file:line rows
+		}
+		line = strings.TrimPrefix(line, "created by ")
+		if i := strings.LastIndex(line, "("); i > 0 && strings.HasSuffix(line, ")") {
+			line = line[:i] // drop the argument list
+		}
+		if i := strings.Index(line, " in goroutine "); i > 0 {
+			line = line[:i]
+		}
+		fns = append(fns, line)
+	}
+	return fns
+}
+
+func ignored(g Goroutine, ignore []string) bool {
+	for _, fn := range functions(g.Stack) {
+		for _, prefix := range ignore {
+			if strings.HasPrefix(fn, prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func currentID() int {
+	buf := make([]byte, 64)
+	n := runtime.Stack(buf, false)
+	g, _ := parse(string(buf[:n]))
+	return g.ID
+}
@@ -0,0 +1,66 @@
+package leakcheck
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recorder is a TB that keeps what Check reports instead of failing.
+type recorder struct{ errs []string }
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errs = append(r.errs, fmt.Sprintf(format, args...))
+}
+
+func blockOn(c chan struct{}) { <-c }
+
+func TestReportsBlockedGoroutine(t *testing.T) {
+	snap := Take()
+	release := make(chan struct{})
+	go blockOn(release)
+
+	leaked := snap.Leaks(WithTimeout(50 * time.Millisecond))
+	var rec recorder
+	snap.Check(&rec, WithTimeout(50*time.Millisecond))
+	close(release)
+
+	if len(leaked) != 1 {
+		t.Fatalf("got %d leaked goroutines, want 1:\n%s", len(leaked), Report(leaked))
+	}
+	if g := leaked[0]; g.State != "chan receive" || g.Top() != "conc/leakcheck.blockOn" {
+		t.Errorf("leaked goroutine is %q in %q, want chan receive in blockOn", g.State, g.Top())
+	}
+	if len(rec.errs) != 1 || !strings.Contains(rec.errs[0], "1 goroutine(s) leaked") {
+		t.Errorf("Check reported %q", rec.errs)
+	}
+	if leaked := snap.Leaks(); len(leaked) != 0 {
+		t.Errorf("released goroutine still reported:\n%s", Report(leaked))
+	}
+}
+
+func TestGracePeriod(t *testing.T) {
+	snap := Take()
+	go time.Sleep(20 * time.Millisecond)
+
+	var rec recorder
+	snap.Check(&rec)
+	if len(rec.errs) != 0 {
+		t.Errorf("goroutine that exits within the timeout reported:\n%s", rec.errs)
+	}
+}
+
+func TestIgnore(t *testing.T) {
+	snap := Take()
+	release := make(chan struct{})
+	defer close(release)
+	go blockOn(release)
+
+	leaked := snap.Leaks(WithTimeout(10*time.Millisecond), Ignore("conc/leakcheck.blockOn"))
+	if len(leaked) != 0 {
+		t.Errorf("ignored goroutine reported:\n%s", Report(leaked))
+	}
+}
@@ -0,0 +1,134 @@
+// Package multiprod is a channel shared by several producers. Only the last
+// producer to finish closes it, so no producer has to know about the others,
+// and a send that comes too late gets ErrClosed instead of a panic.
+package multiprod
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrClosed is returned by Send once the channel is closed or the producer
+// has called Done, and by Add once the channel is closed.
+var ErrClosed = errors.New("multiprod: send on closed channel")
+
+// Chan is a channel with any number of registered producers. It closes
+// exactly once: when the last producer calls Done, or on Close.
+//
+// Register every producer (Add) before starting the first one, or the
+// channel may close in between because the producer count touched zero.
+type Chan[T any] struct {
+	ch   chan T
+	done chan struct{} // closed just before ch, to release blocked senders
+
+	// Senders hold sendMu for reading; shut takes it for writing after closing
+	// done, so ch is never closed under a sender.
+	sendMu sync.RWMutex
+
+	mu        sync.Mutex
+	producers int
+	closed    bool
+}
+
+// New returns an open channel with the given buffer size and no producers.
+func New[T any](buffer int) *Chan[T] {
+	return &Chan[T]{ch: make(chan T, buffer), done: make(chan struct{})}
+}
+
+// C is the receiving side; range over it to read until every producer is done.
+func (c *Chan[T]) C() <-chan T { return c.ch }
+
+// Add registers a new producer. It fails with ErrClosed if the channel has
+// already closed.
+func (c *Chan[T]) Add() (*Producer[T], error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil, ErrClosed
+	}
+	c.producers++
+	return &Producer[T]{c: c}, nil
+}
+
+// Producers reports how many registered producers haven't called Done yet.
+func (c *Chan[T]) Producers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.producers
+}
+
+// Close closes the channel now, whatever the producers are doing: blocked and
+// later sends return ErrClosed. It is for the consumer giving up early;
+// closing more than once is harmless.
+func (c *Chan[T]) Close() {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return
+	}
+	c.closed = true
+	c.mu.Unlock()
+	c.shut()
+}
+
+func (c *Chan[T]) shut() {
+	close(c.done)
+	c.sendMu.Lock()
+	close(c.ch)
+	c.sendMu.Unlock()
+}
+
+// Producer is one registered sender on a Chan.
+type Producer[T any] struct {
+	c        *Chan[T]
+	doneOnce sync.Once
+	finished atomic.Bool
+}
+
+// Send delivers v, blocking while the buffer is full. It returns ErrClosed if
+// the channel is (or becomes) closed or p has already called Done, and ctx's
+// error if ctx ends first.
+func (p *Producer[T]) Send(ctx context.Context, v T) error {
+	if p.finished.Load() {
+		return ErrClosed
+	}
+
+	c := p.c
+	c.sendMu.RLock()
+	defer c.sendMu.RUnlock()
+	select {
+	case <-c.done:
+		return ErrClosed
+	default:
+	}
+	select {
+	case c.ch <- v:
+		return nil
+	case <-c.done:
+		return ErrClosed
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	}
+}
+
+// Done tells the channel p will send no more. The last producer's Done closes
+// the channel. Calling it more than once counts once.
+func (p *Producer[T]) Done() {
+	p.doneOnce.Do(func() {
+		p.finished.Store(true)
+
+		c := p.c
+		c.mu.Lock()
+		c.producers--
+		last := c.producers == 0 && !c.closed
+		if last {
+			c.closed = true
+		}
+		c.mu.Unlock()
+		if last {
+			c.shut()
+		}
+	})
+}
@@ -0,0 +1,59 @@
+package multiprod
+
+import (
+	"conc/leakcheck"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLastProducerCloses(t *testing.T) {
+	defer leakcheck.Take().Check(t)
+
+	c := New[int](0)
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		p, err := c.Add()
+		if err != nil {
+			t.Fatal(err)
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer p.Done()
+			for j := 0; j < 10; j++ {
+				if err := p.Send(context.Background(), i*10+j); err != nil {
+					t.Error(err)
+				}
+			}
+		}(i)
+	}
+	n := 0
+	for range c.C() {
+		n++
+	}
+	wg.Wait()
+	if n != 30 {
+		t.Fatalf("received %d values, want 30", n)
+	}
+	if _, err := c.Add(); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Add after close = %v, want ErrClosed", err)
+	}
+}
+
+func TestCloseReleasesBlockedSend(t *testing.T) {
+	defer leakcheck.Take().Check(t)
+
+	c := New[int](0)
+	p, _ := c.Add()
+	errc := make(chan error, 1)
+	go func() { errc <- p.Send(context.Background(), 1) }()
+	time.Sleep(10 * time.Millisecond)
+	c.Close()
+	if err := <-errc; !errors.Is(err, ErrClosed) {
+		t.Fatalf("Send = %v, want ErrClosed", err)
+	}
+	p.Done()
+}
@@ -0,0 +1,254 @@
+// Package mux selects over a set of channels whose size is only known at run
+// time and can change while receiving.
+//
+// Two implementations share the Mux interface: Reflect is a single
+// reflect.Select over every channel, FanIn runs one forwarding goroutine per
+// channel into a shared channel. BenchmarkRecv compares them.
+package mux
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+)
+
+// ErrClosed is returned by Recv after Close.
+var ErrClosed = errors.New("mux: closed")
+
+// Event is one thing that happened on a channel in the set. Index is the
+// value Add returned for that channel; it stays valid while other channels
+// come and go. When Closed is true the channel was closed and has been
+// removed from the set, and Value is the zero value.
+type Event[T any] struct {
+	Index  int
+	Value  T
+	Closed bool
+}
+
+// Mux multiplexes a changing set of channels. Add and Remove may be called
+// from any goroutine, including while Recv is blocked; Recv is meant for a
+// single consumer.
+type Mux[T any] interface {
+	Add(ch <-chan T) int
+	Remove(index int)
+	Len() int
+	Recv(ctx context.Context) (Event[T], error)
+	Close()
+}
+
+// Reflect implements Mux with reflect.Select. Cheap to add and remove
+// channels, but every Recv walks the full set.
+type Reflect[T any] struct {
+	mu      sync.Mutex
+	chans   map[int]<-chan T
+	next    int
+	closed  bool
+	changed chan struct{} // closed and replaced whenever the set changes
+
+	// Select cases for the current set, rebuilt lazily after a change.
+	cases   []reflect.SelectCase
+	indexes []int
+}
+
+// NewReflect returns an empty Reflect mux.
+func NewReflect[T any]() *Reflect[T] {
+	return &Reflect[T]{chans: map[int]<-chan T{}, changed: make(chan struct{})}
+}
+
+func (m *Reflect[T]) Add(ch <-chan T) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	idx := m.next
+	m.next++
+	m.chans[idx] = ch
+	m.notify()
+	return idx
+}
+
+func (m *Reflect[T]) Remove(index int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.chans[index]; ok {
+		delete(m.chans, index)
+		m.notify()
+	}
+}
+
+func (m *Reflect[T]) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.chans)
+}
+
+func (m *Reflect[T]) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.closed {
+		m.closed = true
+		m.notify()
+	}
+}
+
+// notify wakes a blocked Recv so it picks up the new set. Callers hold m.mu.
+func (m *Reflect[T]) notify() {
+	close(m.changed)
+	m.changed = make(chan struct{})
+	m.cases = nil
+}
+
+// Recv blocks until a channel in the set delivers a value or is closed. With
+// an empty set it waits for an Add, Close or ctx.
+func (m *Reflect[T]) Recv(ctx context.Context) (Event[T], error) {
+	for {
+		m.mu.Lock()
+		if m.closed {
+			m.mu.Unlock()
+			return Event[T]{}, ErrClosed
+		}
+		if m.cases == nil {
+			m.rebuild()
+		}
+		m.cases[0].Chan = reflect.ValueOf(ctx.Done())
+		cases := m.cases
+		indexes := m.indexes
+		m.mu.Unlock()
+
+		chosen, v, ok := reflect.Select(cases)
+		switch chosen {
+		case 0:
+			return Event[T]{}, ctx.Err()
+		case 1:
+			continue // set changed
+		}
+
+		idx := indexes[chosen]
+		if !ok {
+			m.Remove(idx)
+			return Event[T]{Index: idx, Closed: true}, nil
+		}
+		return Event[T]{Index: idx, Value: v.Interface().(T)}, nil
+	}
+}
+
+// rebuild lays out ctx.Done (filled in by Recv) and changed as cases 0 and 1,
+// then the channels. Callers hold m.mu.
+func (m *Reflect[T]) rebuild() {
+	m.cases = append(m.cases[:0],
+		reflect.SelectCase{Dir: reflect.SelectRecv},
+		reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(m.changed)},
+	)
+	m.indexes = append(m.indexes[:0], -1, -1)
+	for idx, ch := range m.chans {
+		m.cases = append(m.cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ch)})
+		m.indexes = append(m.indexes, idx)
+	}
+}
+
+// FanIn implements Mux with one forwarding goroutine per channel. Recv is a
+// plain channel receive however many channels there are, at the cost of a
+// goroutine each. A value the forwarder already took from a channel just
+// before Remove is dropped.
+type FanIn[T any] struct {
+	out  chan Event[T]
+	done chan struct{}
+
+	mu     sync.Mutex
+	stops  map[int]chan struct{}
+	next   int
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// NewFanIn returns an empty FanIn mux.
+func NewFanIn[T any]() *FanIn[T] {
+	return &FanIn[T]{out: make(chan Event[T]), done: make(chan struct{}), stops: map[int]chan struct{}{}}
+}
+
+func (m *FanIn[T]) Add(ch <-chan T) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	idx := m.next
+	m.next++
+	if m.closed {
+		return idx
+	}
+	stop := make(chan struct{})
+	m.stops[idx] = stop
+	m.wg.Add(1)
+	go m.forward(idx, ch, stop)
+	return idx
+}
+
+func (m *FanIn[T]) forward(idx int, ch <-chan T, stop <-chan struct{}) {
+	defer m.wg.Done()
+	for {
+		var ev Event[T]
+		select {
+		case v, ok := <-ch:
+			ev = Event[T]{Index: idx, Value: v, Closed: !ok}
+		case <-stop:
+			return
+		}
+		if ev.Closed {
+			// Leave the set before reporting, so Len is already right when
+			// the consumer sees the event.
+			m.mu.Lock()
+			delete(m.stops, idx)
+			m.mu.Unlock()
+		}
+		select {
+		case m.out <- ev:
+		case <-stop:
+			return
+		case <-m.done:
+			return
+		}
+		if ev.Closed {
+			return
+		}
+	}
+}
+
+func (m *FanIn[T]) Remove(index int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if stop, ok := m.stops[index]; ok {
+		delete(m.stops, index)
+		close(stop)
+	}
+}
+
+func (m *FanIn[T]) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.stops)
+}
+
+// Close stops every forwarder and waits for them to exit.
+func (m *FanIn[T]) Close() {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return
+	}
+	m.closed = true
+	for idx, stop := range m.stops {
+		delete(m.stops, idx)
+		close(stop)
+	}
+	close(m.done)
+	m.mu.Unlock()
+	m.wg.Wait()
+}
+
+func (m *FanIn[T]) Recv(ctx context.Context) (Event[T], error) {
+	select {
+	case ev := <-m.out:
+		return ev, nil
+	case <-m.done:
+		return Event[T]{}, ErrClosed
+	case <-ctx.Done():
+		return Event[T]{}, ctx.Err()
+	}
+}
@@ -0,0 +1,195 @@
+package mux
+
+import (
+	"conc/leakcheck"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+var impls = []struct {
+	name string
+	new  func() Mux[int]
+}{
+	{"reflect", func() Mux[int] { return NewReflect[int]() }},
+	{"fan-in", func() Mux[int] { return NewFanIn[int]() }},
+}
+
+func TestRecvReportsValuesAndClose(t *testing.T) {
+	for _, impl := range impls {
+		t.Run(impl.name, func(t *testing.T) {
+			defer leakcheck.Take().Check(t)
+
+			m := impl.new()
+			defer m.Close()
+			a, b := make(chan int, 1), make(chan int, 1)
+			ia, ib := m.Add(a), m.Add(b)
+			a <- 1
+			close(b)
+
+			got := map[int]Event[int]{}
+			for m.Len() > 1 || len(got) < 2 {
+				ev, err := m.Recv(context.Background())
+				if err != nil {
+					t.Fatal(err)
+				}
+				got[ev.Index] = ev
+			}
+			if ev := got[ia]; ev.Value != 1 || ev.Closed {
+				t.Errorf("channel a: got %+v", ev)
+			}
+			if ev := got[ib]; !ev.Closed {
+				t.Errorf("channel b: got %+v, want Closed", ev)
+			}
+			if n := m.Len(); n != 1 {
+				t.Errorf("Len = %d after b closed, want 1", n)
+			}
+		})
+	}
+}
+
+type recvResult struct {
+	ev  Event[int]
+	err error
+}
+
+// recvAsync starts a Recv and waits until it has had time to block.
+func recvAsync(m Mux[int]) <-chan recvResult {
+	res := make(chan recvResult, 1)
+	go func() {
+		ev, err := m.Recv(context.Background())
+		res <- recvResult{ev, err}
+	}()
+	time.Sleep(10 * time.Millisecond)
+	return res
+}
+
+func TestAddWhileRecvBlocked(t *testing.T) {
+	for _, impl := range impls {
+		t.Run(impl.name, func(t *testing.T) {
+			defer leakcheck.Take().Check(t)
+
+			m := impl.new()
+			defer m.Close()
+			m.Add(make(chan int))
+			res := recvAsync(m)
+
+			ch := make(chan int, 1)
+			ch <- 7
+			idx := m.Add(ch)
+			select {
+			case r := <-res:
+				if r.err != nil || r.ev.Index != idx || r.ev.Value != 7 {
+					t.Errorf("Recv = %+v, %v; want 7 from channel %d", r.ev, r.err, idx)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("blocked Recv did not pick up the added channel")
+			}
+		})
+	}
+}
+
+func TestRemoveWhileRecvBlocked(t *testing.T) {
+	for _, impl := range impls {
+		t.Run(impl.name, func(t *testing.T) {
+			defer leakcheck.Take().Check(t)
+
+			m := impl.new()
+			defer m.Close()
+			a, b := make(chan int, 1), make(chan int, 1)
+			ia, ib := m.Add(a), m.Add(b)
+			res := recvAsync(m)
+
+			m.Remove(ia)
+			if n := m.Len(); n != 1 {
+				t.Errorf("Len = %d after Remove, want 1", n)
+			}
+			a <- 1 // no longer watched
+			b <- 2
+			r := <-res
+			if r.err != nil || r.ev.Index != ib || r.ev.Value != 2 {
+				t.Errorf("Recv = %+v, %v; want 2 from channel %d", r.ev, r.err, ib)
+			}
+			if len(a) != 1 {
+				t.Errorf("value sent on a removed channel was received")
+			}
+		})
+	}
+}
+
+func TestCloseUnblocksRecv(t *testing.T) {
+	for _, impl := range impls {
+		t.Run(impl.name, func(t *testing.T) {
+			defer leakcheck.Take().Check(t)
+
+			m := impl.new()
+			m.Add(make(chan int))
+			res := recvAsync(m)
+
+			m.Close()
+			select {
+			case r := <-res:
+				if !errors.Is(r.err, ErrClosed) {
+					t.Errorf("blocked Recv = %+v, %v; want ErrClosed", r.ev, r.err)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("Close did not release the blocked Recv")
+			}
+			if _, err := m.Recv(context.Background()); !errors.Is(err, ErrClosed) {
+				t.Errorf("Recv after Close = %v, want ErrClosed", err)
+			}
+		})
+	}
+}
+
+// BenchmarkRecv pushes b.N values spread over a growing number of channels
+// through each multiplexer; ns/op is the cost per received value.
+// reflect.Select does O(channels) work per receive; the fan-in mux pays a
+// goroutine per channel but each receive is a single channel read.
+func BenchmarkRecv(b *testing.B) {
+	for _, impl := range impls {
+		for _, n := range []int{2, 8, 32, 128, 512} {
+			b.Run(fmt.Sprintf("%s/channels=%d", impl.name, n), func(b *testing.B) {
+				benchRecv(b, impl.new(), n)
+			})
+		}
+	}
+}
+
+func benchRecv(b *testing.B, m Mux[int], n int) {
+	defer m.Close()
+	chans := make([]chan int, n)
+	for i := range chans {
+		chans[i] = make(chan int, 16)
+		m.Add(chans[i])
+	}
+	b.ResetTimer()
+
+	var wg sync.WaitGroup
+	for i, ch := range chans {
+		per := b.N / n
+		if i < b.N%n {
+			per++
+		}
+		wg.Add(1)
+		go func(ch chan<- int, per int) {
+			defer wg.Done()
+			for v := 0; v < per; v++ {
+				ch <- v
+			}
+			close(ch)
+		}(ch, per)
+	}
+
+	ctx := context.Background()
+	for m.Len() > 0 {
+		if _, err := m.Recv(ctx); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.StopTimer()
+	wg.Wait()
+}
@@ -0,0 +1,79 @@
+package pool
+
+import "time"
+
+type config struct {
+	workers   int
+	queueSize int
+	ordered   bool
+	discard   bool
+	failFast  bool
+	scale     scaling
+	retry     *RetryPolicy
+}
+
+// Option configures a Pool.
+type Option func(*config)
+
+// WithWorkers sets how many jobs run at once (default 1).
+func WithWorkers(n int) Option {
+	return func(c *config) { c.workers = n }
+}
+
+// WithQueueSize sets how many submitted jobs may wait for a worker before
+// Submit blocks (default 0, i.e. Submit hands off directly to a worker).
+func WithQueueSize(n int) Option {
+	return func(c *config) { c.queueSize = n }
+}
+
+// WithOrdered delivers results in submission order instead of completion order.
+func WithOrdered() Option {
+	return func(c *config) { c.ordered = true }
+}
+
+// WithDiscardResults drops every result instead of delivering it on Results,
+// for callers that only care about side effects and Wait's error. Results is
+// closed straight away, and nothing has to read it for the pool to shut down.
+func WithDiscardResults() Option {
+	return func(c *config) { c.discard = true }
+}
+
+// WithFailFast cancels the pool on the first job error.
+func WithFailFast() Option {
+	return func(c *config) { c.failFast = true }
+}
+
+// WithMaxWorkers lets the pool grow beyond WithWorkers, up to n, while jobs are
+// backing up. By default it grows whenever Submit finds the queue full;
+// WithScaleUpDepth and WithScaleUpWait add earlier triggers. Workers added this
+// way retire after WithIdleTimeout without work.
+func WithMaxWorkers(n int) Option {
+	return func(c *config) { c.scale.max = n }
+}
+
+// WithScaleUpDepth grows the pool once n or more jobs are queued.
+func WithScaleUpDepth(n int) Option {
+	return func(c *config) { c.scale.depth = n }
+}
+
+// WithScaleUpWait grows the pool when a job waited longer than d for a worker.
+func WithScaleUpWait(d time.Duration) Option {
+	return func(c *config) { c.scale.wait = d }
+}
+
+// WithIdleTimeout sets how long an added worker may sit idle before it retires (default 1s).
+func WithIdleTimeout(d time.Duration) Option {
+	return func(c *config) { c.scale.idle = d }
+}
+
+// OnScale registers fn to be called after every scaling event. fn runs on the
+// goroutine that triggered the change, so it should return quickly.
+func OnScale(fn func(ScaleEvent)) Option {
+	return func(c *config) { c.scale.onScale = fn }
+}
+
+// WithRetry gives every job rp unless Submit overrides it with Retry. Jobs
+// that exhaust it are moved to the pool's dead-letter queue.
+func WithRetry(rp RetryPolicy) Option {
+	return func(c *config) { c.retry = &rp }
+}
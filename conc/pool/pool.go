@@ -0,0 +1,368 @@
+package pool
+
+import (
+	"conc/safego"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrClosed is returned by Submit once Close (or Wait) has been called.
+var ErrClosed = errors.New("pool: submit on closed pool")
+
+// Func is the work each job runs. ctx is cancelled when the job's own
+// context (the one passed to Submit) or the pool's context is done.
+type Func[In, Out any] func(ctx context.Context, in In) (Out, error)
+
+// Result is what a worker produced for one submitted job.
+// Index is the submission order, starting at 0.
+type Result[In, Out any] struct {
+	Index    int
+	Worker   int
+	Attempts int
+	In       In
+	Out      Out
+	Err      error
+}
+
+type job[In any] struct {
+	ctx    context.Context
+	index  int
+	in     In
+	queued time.Time
+	retry  *RetryPolicy
+}
+
+type workerKey struct{}
+
+// WorkerID returns the 1-based id of the worker running the job that owns ctx,
+// or 0 if ctx did not come from a pool.
+func WorkerID(ctx context.Context) int {
+	id, _ := ctx.Value(workerKey{}).(int)
+	return id
+}
+
+// Pool runs Func over submitted jobs with a fixed number of workers, or with
+// WithMaxWorkers, a number that grows under backlog and shrinks when idle.
+//
+// Submit blocks while the input queue is full, so producers get backpressure.
+// Workers never block on a slow reader: results go through an internal buffer
+// and come out of Results at the reader's pace, so Close and Wait can't
+// deadlock on an unread Results channel. Close stops intake; Wait does the same
+// and then waits for every worker. Both are safe to call more than once.
+//
+// A job that panics doesn't take its worker down: the panic comes back as the
+// job's error, a *safego.PanicError with the stack.
+//
+// Cancelling the pool's context (or the first error under WithFailFast) stops
+// the workers, drops jobs still queued and releases anything buffered for Results.
+type Pool[In, Out any] struct {
+	fn       Func[In, Out]
+	ctx      context.Context
+	cancel   context.CancelCauseFunc
+	ordered  bool
+	discard  bool
+	failFast bool
+	scale    scaling
+	retry    *RetryPolicy
+	dlq      *DeadLetterQueue[In]
+	stats    stats
+
+	jobs chan job[In]
+	raw  chan Result[In, Out]
+	out  chan Result[In, Out]
+	done chan struct{}
+	wg   sync.WaitGroup
+
+	scaleMu  sync.Mutex // guards spawning so wg.Add never races a wg.Wait at zero
+	size     atomic.Int32
+	workerID int
+
+	submitMu sync.Mutex // serializes Submit so indexes match queue order
+	next     int
+
+	mu      sync.Mutex // guards closed
+	closed  bool
+	closing chan struct{} // closed by Close; releases a Submit blocked on a full queue
+
+	errMu sync.Mutex
+	errs  []error
+}
+
+// New starts a pool of workers running fn. The pool lives until ctx is
+// cancelled or until Wait returns and Results has been drained; a caller that
+// never reads Results should pass WithDiscardResults, or the results pile up
+// in memory for as long as the process runs.
+func New[In, Out any](ctx context.Context, fn Func[In, Out], opts ...Option) *Pool[In, Out] {
+	cfg := config{workers: 1, scale: scaling{idle: time.Second}}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.workers < 1 {
+		cfg.workers = 1
+	}
+	if cfg.queueSize < 0 {
+		cfg.queueSize = 0
+	}
+	if cfg.scale.max < cfg.workers {
+		cfg.scale.max = cfg.workers
+	}
+	cfg.scale.min = cfg.workers
+
+	p := &Pool[In, Out]{
+		fn:       fn,
+		ordered:  cfg.ordered,
+		discard:  cfg.discard,
+		failFast: cfg.failFast,
+		scale:    cfg.scale,
+		retry:    cfg.retry,
+		dlq:      &DeadLetterQueue[In]{},
+		jobs:     make(chan job[In], cfg.queueSize),
+		raw:      make(chan Result[In, Out]),
+		out:      make(chan Result[In, Out]),
+		done:     make(chan struct{}),
+		closing:  make(chan struct{}),
+	}
+	p.ctx, p.cancel = context.WithCancelCause(ctx)
+
+	p.size.Store(int32(cfg.workers))
+	p.wg.Add(cfg.workers)
+	for p.workerID < cfg.workers {
+		p.workerID++
+		p.start(p.workerID)
+	}
+	go func() {
+		p.wg.Wait()
+		close(p.raw)
+		close(p.done)
+	}()
+	go p.forward()
+
+	return p
+}
+
+// Submit queues in for processing. It blocks while the queue is full and
+// returns early with ctx's error, the pool's cancellation cause, or ErrClosed.
+// ctx also becomes the parent of the job's context.
+func (p *Pool[In, Out]) Submit(ctx context.Context, in In, opts ...JobOption) error {
+	jc := jobConfig{retry: p.retry}
+	for _, opt := range opts {
+		opt(&jc)
+	}
+
+	p.submitMu.Lock()
+	defer p.submitMu.Unlock()
+	select {
+	case <-p.closing:
+		return ErrClosed
+	default:
+	}
+
+	j := job[In]{ctx: ctx, index: p.next, in: in, queued: time.Now(), retry: jc.retry}
+	select {
+	case p.jobs <- j:
+		p.next++
+		if p.scale.depth > 0 && len(p.jobs) >= p.scale.depth {
+			p.grow(ReasonQueueDepth)
+		}
+		return nil
+	default:
+		// Every worker is busy and the queue is full.
+		p.grow(ReasonQueueFull)
+	}
+
+	select {
+	case p.jobs <- j:
+		p.next++
+		return nil
+	case <-p.closing:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-p.ctx.Done():
+		return context.Cause(p.ctx)
+	}
+}
+
+// Results returns the output stream. It is closed once every submitted job has
+// been delivered, or once the workers have stopped after a cancel.
+func (p *Pool[In, Out]) Results() <-chan Result[In, Out] {
+	return p.out
+}
+
+// Close stops accepting new jobs. Jobs already queued still run. A Submit
+// blocked on a full queue returns ErrClosed rather than holding Close up.
+func (p *Pool[In, Out]) Close() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	close(p.closing)
+	p.mu.Unlock()
+
+	// Any Submit still holding submitMu has just been released by closing,
+	// so this only waits for it to return.
+	p.submitMu.Lock()
+	close(p.jobs)
+	p.submitMu.Unlock()
+}
+
+// Wait closes the pool, blocks until every worker has exited and returns the
+// job errors joined together (nil if every job succeeded).
+func (p *Pool[In, Out]) Wait() error {
+	p.Close()
+	<-p.done
+	p.errMu.Lock()
+	defer p.errMu.Unlock()
+	return errors.Join(p.errs...)
+}
+
+// Cancel aborts the pool with cause. Running jobs see their context cancelled,
+// queued jobs are dropped and so are results nobody has read yet.
+func (p *Pool[In, Out]) Cancel(cause error) {
+	p.cancel(cause)
+}
+
+// start launches worker id through safego, so its goroutine is labelled
+// "pool worker <id>" in profiles and goroutine dumps.
+func (p *Pool[In, Out]) start(id int) {
+	safego.Go(p.ctx, fmt.Sprintf("pool worker %d", id), func(context.Context) {
+		p.work(id)
+	})
+}
+
+// Size reports how many workers are currently running.
+func (p *Pool[In, Out]) Size() int {
+	return int(p.size.Load())
+}
+
+func (p *Pool[In, Out]) work(id int) {
+	defer p.wg.Done()
+	for {
+		// Workers added by grow retire after sitting idle; the first
+		// WithWorkers ones live as long as the pool.
+		var (
+			timer *time.Timer
+			idle  <-chan time.Time
+		)
+		if id > p.scale.min {
+			timer = time.NewTimer(p.scale.idle)
+			idle = timer.C
+		}
+
+		select {
+		case j, ok := <-p.jobs:
+			if timer != nil {
+				timer.Stop()
+			}
+			if !ok {
+				p.exit()
+				return
+			}
+			if p.scale.wait > 0 && time.Since(j.queued) > p.scale.wait {
+				p.grow(ReasonWaitTime)
+			}
+			p.raw <- p.run(id, j)
+		case <-idle:
+			p.shrink()
+			return
+		case <-p.ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+			p.exit()
+			return
+		}
+	}
+}
+
+func (p *Pool[In, Out]) run(id int, j job[In]) Result[In, Out] {
+	r := Result[In, Out]{Index: j.index, Worker: id, In: j.in}
+
+	ctx, cancel := context.WithCancelCause(j.ctx)
+	defer cancel(nil)
+	stop := context.AfterFunc(p.ctx, func() { cancel(context.Cause(p.ctx)) })
+	defer stop()
+
+	if ctx.Err() != nil {
+		r.Err = context.Cause(ctx)
+		p.stats.cancelled.Add(1)
+	} else {
+		p.attempt(context.WithValue(ctx, workerKey{}, id), j, &r)
+	}
+
+	if r.Err != nil {
+		p.errMu.Lock()
+		p.errs = append(p.errs, r.Err)
+		p.errMu.Unlock()
+		if p.failFast {
+			p.cancel(r.Err)
+		}
+	}
+	return r
+}
+
+// forward moves results from the workers to Results, buffering as much as it
+// needs so that workers never wait on a slow reader. In ordered mode results
+// are held back until every earlier index has been sent.
+func (p *Pool[In, Out]) forward() {
+	if p.discard {
+		close(p.out)
+		for range p.raw {
+		}
+		return
+	}
+	defer close(p.out)
+
+	var (
+		queue   []Result[In, Out]
+		pending = map[int]Result[In, Out]{}
+		next    int
+		raw     = p.raw
+		stopped = p.ctx.Done()
+	)
+	for raw != nil || len(queue) > 0 {
+		var (
+			out  chan<- Result[In, Out]
+			head Result[In, Out]
+		)
+		if len(queue) > 0 {
+			out, head = p.out, queue[0]
+		}
+
+		select {
+		case r, ok := <-raw:
+			if !ok {
+				raw = nil
+				continue
+			}
+			if stopped == nil {
+				continue // cancelled: keep draining workers, drop output
+			}
+			if !p.ordered {
+				queue = append(queue, r)
+				continue
+			}
+			pending[r.Index] = r
+			for {
+				r, ok := pending[next]
+				if !ok {
+					break
+				}
+				delete(pending, next)
+				queue = append(queue, r)
+				next++
+			}
+		case out <- head:
+			queue[0] = Result[In, Out]{}
+			queue = queue[1:]
+		case <-stopped:
+			stopped, queue, pending = nil, nil, nil
+		}
+	}
+}
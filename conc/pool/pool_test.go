@@ -0,0 +1,121 @@
+package pool
+
+import (
+	"conc/leakcheck"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func double(_ context.Context, n int) (int, error) { return 2 * n, nil }
+
+func TestOrderedResults(t *testing.T) {
+	defer leakcheck.Take().Check(t)
+
+	p := New(context.Background(), double, WithWorkers(4), WithOrdered())
+	go func() {
+		for i := 0; i < 100; i++ {
+			if err := p.Submit(context.Background(), i); err != nil {
+				t.Error(err)
+			}
+		}
+		p.Close()
+	}()
+	want := 0
+	for r := range p.Results() {
+		if r.Index != want || r.Out != 2*want {
+			t.Fatalf("result %d: got index %d out %d", want, r.Index, r.Out)
+		}
+		want++
+	}
+	if want != 100 {
+		t.Fatalf("got %d results, want 100", want)
+	}
+	if err := p.Wait(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCloseReleasesBlockedSubmit(t *testing.T) {
+	defer leakcheck.Take().Check(t)
+
+	release := make(chan struct{})
+	p := New(context.Background(), func(ctx context.Context, n int) (int, error) {
+		<-release
+		return n, nil
+	}, WithDiscardResults())
+
+	// The only worker is busy and the queue is unbuffered, so the next
+	// Submit blocks.
+	if err := p.Submit(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	errc := make(chan error, 1)
+	go func() { errc <- p.Submit(context.Background(), 2) }()
+	time.Sleep(20 * time.Millisecond)
+
+	closed := make(chan struct{})
+	go func() {
+		p.Close()
+		close(closed)
+	}()
+	select {
+	case err := <-errc:
+		if !errors.Is(err, ErrClosed) {
+			t.Fatalf("blocked Submit returned %v, want ErrClosed", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Close did not release the blocked Submit")
+	}
+	<-closed
+	close(release)
+	if err := p.Wait(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDiscardResults(t *testing.T) {
+	defer leakcheck.Take().Check(t)
+
+	p := New(context.Background(), double, WithWorkers(2), WithDiscardResults())
+	for i := 0; i < 10; i++ {
+		if err := p.Submit(context.Background(), i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Nothing reads Results, yet Wait returns and Results is already closed.
+	if err := p.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-p.Results(); ok {
+		t.Fatal("Results delivered a value in discard mode")
+	}
+	if got := p.Stats().FirstTry; got != 10 {
+		t.Fatalf("FirstTry = %d, want 10", got)
+	}
+}
+
+func TestCancelledDuringBackoff(t *testing.T) {
+	defer leakcheck.Take().Check(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	failing := func(context.Context, int) (int, error) { return 0, errors.New("boom") }
+	p := New(ctx, failing, WithDiscardResults(),
+		WithRetry(RetryPolicy{MaxAttempts: 5, Backoff: time.Hour}))
+	if err := p.Submit(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	for p.Stats().Retries == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := p.Wait(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Wait = %v, want context.Canceled", err)
+	}
+	st := p.Stats()
+	if st.Cancelled != 1 || st.DeadLettered != 0 || st.Failed != 0 {
+		t.Fatalf("stats = %+v, want exactly one cancelled job", st)
+	}
+}
@@ -0,0 +1,208 @@
+package pool
+
+import (
+	"conc/safego"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RetryPolicy says how often a failing job is retried and how long to wait
+// between attempts. Attempt n (n >= 2) waits Backoff * Multiplier^(n-2),
+// capped at MaxBackoff. A job that still fails after MaxAttempts goes to the
+// pool's dead-letter queue.
+type RetryPolicy struct {
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration // 0 means no cap
+	Multiplier  float64       // 0 means 2
+}
+
+func (rp RetryPolicy) delay(attempt int) time.Duration {
+	mult := rp.Multiplier
+	if mult == 0 {
+		mult = 2
+	}
+	d := float64(rp.Backoff)
+	for i := 2; i < attempt && (rp.MaxBackoff == 0 || d < float64(rp.MaxBackoff)); i++ {
+		d *= mult
+	}
+	if rp.MaxBackoff > 0 && d > float64(rp.MaxBackoff) {
+		return rp.MaxBackoff
+	}
+	return time.Duration(d)
+}
+
+type jobConfig struct {
+	retry *RetryPolicy
+}
+
+// JobOption configures a single Submit.
+type JobOption func(*jobConfig)
+
+// Retry overrides the pool's retry policy (see WithRetry) for one job.
+func Retry(rp RetryPolicy) JobOption {
+	return func(c *jobConfig) { c.retry = &rp }
+}
+
+// DeadLetter is a job that failed every attempt its retry policy allowed.
+type DeadLetter[In any] struct {
+	In       In        `json:"in"`
+	Err      string    `json:"error"`
+	Attempts int       `json:"attempts"`
+	Time     time.Time `json:"time"`
+}
+
+// DeadLetterQueue holds jobs that ran out of retries so they can be inspected,
+// exported, and re-driven later with Pool.Redrive.
+type DeadLetterQueue[In any] struct {
+	mu      sync.Mutex
+	letters []DeadLetter[In]
+}
+
+func (q *DeadLetterQueue[In]) add(dl DeadLetter[In]) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.letters = append(q.letters, dl)
+}
+
+// Len reports how many jobs are dead-lettered.
+func (q *DeadLetterQueue[In]) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.letters)
+}
+
+// List returns a copy of the dead letters, oldest first.
+func (q *DeadLetterQueue[In]) List() []DeadLetter[In] {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]DeadLetter[In](nil), q.letters...)
+}
+
+// Drain removes and returns every dead letter.
+func (q *DeadLetterQueue[In]) Drain() []DeadLetter[In] {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	letters := q.letters
+	q.letters = nil
+	return letters
+}
+
+// WriteJSON exports the dead letters as a JSON array.
+func (q *DeadLetterQueue[In]) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(q.List())
+}
+
+// ReadJSON appends dead letters previously exported with WriteJSON, e.g. to
+// re-drive them in a later run.
+func (q *DeadLetterQueue[In]) ReadJSON(r io.Reader) error {
+	var letters []DeadLetter[In]
+	if err := json.NewDecoder(r).Decode(&letters); err != nil {
+		return err
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.letters = append(q.letters, letters...)
+	return nil
+}
+
+// Stats counts how jobs ended. Failed is jobs that errored without a retry
+// policy; jobs with one end up in DeadLettered instead. Cancelled is jobs
+// whose context ended before they started, while they ran and failed, or
+// while they waited to be retried. Every job a worker picked up lands in exactly one of FirstTry,
+// AfterRetry, DeadLettered, Failed and Cancelled.
+type Stats struct {
+	FirstTry     int64
+	AfterRetry   int64
+	DeadLettered int64
+	Failed       int64
+	Cancelled    int64
+	Retries      int64
+}
+
+type stats struct {
+	firstTry, afterRetry, deadLettered, failed, cancelled, retries atomic.Int64
+}
+
+// Stats returns the job outcome counters so far.
+func (p *Pool[In, Out]) Stats() Stats {
+	return Stats{
+		FirstTry:     p.stats.firstTry.Load(),
+		AfterRetry:   p.stats.afterRetry.Load(),
+		DeadLettered: p.stats.deadLettered.Load(),
+		Failed:       p.stats.failed.Load(),
+		Cancelled:    p.stats.cancelled.Load(),
+		Retries:      p.stats.retries.Load(),
+	}
+}
+
+// DeadLetters returns the pool's dead-letter queue.
+func (p *Pool[In, Out]) DeadLetters() *DeadLetterQueue[In] {
+	return p.dlq
+}
+
+// Redrive drains the dead-letter queue and submits every job again with a
+// fresh set of attempts, so it has to run before Close or Wait. Jobs that
+// can't be submitted go back to the queue.
+func (p *Pool[In, Out]) Redrive(ctx context.Context, opts ...JobOption) (int, error) {
+	letters := p.dlq.Drain()
+	for i, dl := range letters {
+		if err := p.Submit(ctx, dl.In, opts...); err != nil {
+			for _, rest := range letters[i:] {
+				p.dlq.add(rest)
+			}
+			return i, err
+		}
+	}
+	return len(letters), nil
+}
+
+// attempt runs the job until it succeeds, its retry policy gives up, or ctx is done.
+func (p *Pool[In, Out]) attempt(ctx context.Context, j job[In], r *Result[In, Out]) {
+	rp := j.retry
+	for {
+		r.Attempts++
+		r.Err = safego.Call(fmt.Sprintf("pool worker %d", r.Worker), func() error {
+			var err error
+			r.Out, err = p.fn(ctx, j.in)
+			return err
+		})
+		if r.Err == nil || ctx.Err() != nil || rp == nil || r.Attempts >= rp.MaxAttempts {
+			break
+		}
+
+		p.stats.retries.Add(1)
+		t := time.NewTimer(rp.delay(r.Attempts + 1))
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			r.Err = fmt.Errorf("retry cancelled after %d attempts: %w (last error: %v)", r.Attempts, context.Cause(ctx), r.Err)
+			p.stats.cancelled.Add(1)
+			return
+		}
+	}
+
+	switch {
+	case r.Err == nil && r.Attempts == 1:
+		p.stats.firstTry.Add(1)
+	case r.Err == nil:
+		p.stats.afterRetry.Add(1)
+	case ctx.Err() != nil:
+		// The job most likely failed because it was cancelled; retrying or
+		// dead-lettering it would only repeat that.
+		p.stats.cancelled.Add(1)
+	case rp != nil:
+		p.stats.deadLettered.Add(1)
+		p.dlq.add(DeadLetter[In]{In: j.in, Err: r.Err.Error(), Attempts: r.Attempts, Time: time.Now()})
+	default:
+		p.stats.failed.Add(1)
+	}
+}
@@ -0,0 +1,168 @@
+package pool
+
+import (
+	"bytes"
+	"conc/leakcheck"
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var errBoom = errors.New("boom")
+
+func TestRetryStats(t *testing.T) {
+	defer leakcheck.Take().Check(t)
+
+	var (
+		mu    sync.Mutex
+		tries = make(map[int]int)
+	)
+	// Odd inputs fail twice before succeeding.
+	flaky := func(_ context.Context, n int) (int, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		tries[n]++
+		if n%2 == 1 && tries[n] < 3 {
+			return 0, errBoom
+		}
+		return n, nil
+	}
+	p := New(context.Background(), flaky, WithWorkers(3), WithDiscardResults(),
+		WithRetry(RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond}))
+	for i := 0; i < 10; i++ {
+		if err := p.Submit(context.Background(), i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := p.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	want := Stats{FirstTry: 5, AfterRetry: 5, Retries: 10}
+	if st := p.Stats(); st != want {
+		t.Errorf("stats = %+v, want %+v", st, want)
+	}
+}
+
+func TestDeadLettersRoundTripAndRedrive(t *testing.T) {
+	defer leakcheck.Take().Check(t)
+
+	var healed atomic.Bool
+	fn := func(_ context.Context, n int) (int, error) {
+		if !healed.Load() {
+			return 0, errBoom
+		}
+		return n, nil
+	}
+	p := New(context.Background(), fn, WithWorkers(2), WithDiscardResults(),
+		WithRetry(RetryPolicy{MaxAttempts: 2, Backoff: time.Millisecond}))
+	for i := 0; i < 3; i++ {
+		if err := p.Submit(context.Background(), i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for p.DeadLetters().Len() < 3 {
+		time.Sleep(time.Millisecond)
+	}
+
+	var buf bytes.Buffer
+	if err := p.DeadLetters().WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var restored DeadLetterQueue[int]
+	if err := restored.ReadJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	orig, got := p.DeadLetters().List(), restored.List()
+	if len(got) != len(orig) {
+		t.Fatalf("read back %d dead letters, want %d", len(got), len(orig))
+	}
+	for i := range orig {
+		o, g := orig[i], got[i]
+		if g.In != o.In || g.Err != o.Err || g.Attempts != 2 || !g.Time.Equal(o.Time) {
+			t.Errorf("dead letter %d: got %+v, want %+v", i, g, o)
+		}
+	}
+
+	healed.Store(true)
+	n, err := p.Redrive(context.Background())
+	if err != nil || n != 3 {
+		t.Fatalf("Redrive = %d, %v; want 3, nil", n, err)
+	}
+	// The first round's failures are still part of Wait's error.
+	if err := p.Wait(); !errors.Is(err, errBoom) {
+		t.Fatalf("Wait = %v, want the earlier failures", err)
+	}
+	if p.DeadLetters().Len() != 0 {
+		t.Errorf("%d dead letters left after Redrive", p.DeadLetters().Len())
+	}
+	if st := p.Stats(); st.DeadLettered != 3 || st.FirstTry != 3 {
+		t.Errorf("stats = %+v, want 3 dead-lettered and 3 redriven on the first try", st)
+	}
+}
+
+func TestFailFastCancelsSiblings(t *testing.T) {
+	defer leakcheck.Take().Check(t)
+
+	var started sync.WaitGroup
+	started.Add(2)
+	fn := func(ctx context.Context, n int) (int, error) {
+		if n == 0 {
+			started.Wait()
+			return 0, errBoom
+		}
+		started.Done()
+		<-ctx.Done()
+		return 0, ctx.Err()
+	}
+	p := New(context.Background(), fn, WithWorkers(3), WithDiscardResults(), WithFailFast())
+	for _, n := range []int{1, 2, 0} {
+		if err := p.Submit(context.Background(), n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := p.Wait(); !errors.Is(err, errBoom) {
+		t.Fatalf("Wait = %v, want errBoom", err)
+	}
+	if st := p.Stats(); st.Failed != 1 || st.Cancelled != 2 {
+		t.Errorf("stats = %+v, want 1 failed and 2 cancelled", st)
+	}
+}
+
+func TestCancelledMidAttempt(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		opts []Option
+	}{
+		{"no retry", nil},
+		{"retry", []Option{WithRetry(RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond})}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			defer leakcheck.Take().Check(t)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			running := make(chan struct{})
+			fn := func(ctx context.Context, _ int) (int, error) {
+				close(running)
+				<-ctx.Done()
+				return 0, ctx.Err()
+			}
+			p := New(ctx, fn, append(tc.opts, WithDiscardResults())...)
+			if err := p.Submit(context.Background(), 1); err != nil {
+				t.Fatal(err)
+			}
+			<-running
+			cancel()
+			if err := p.Wait(); !errors.Is(err, context.Canceled) {
+				t.Fatalf("Wait = %v, want context.Canceled", err)
+			}
+			want := Stats{Cancelled: 1}
+			if st := p.Stats(); st != want {
+				t.Errorf("stats = %+v, want %+v", st, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package pool
+
+import "time"
+
+// ScaleReason says why the pool changed size.
+type ScaleReason string
+
+const (
+	ReasonQueueFull  ScaleReason = "queue full"
+	ReasonQueueDepth ScaleReason = "queue depth"
+	ReasonWaitTime   ScaleReason = "wait time"
+	ReasonIdle       ScaleReason = "idle"
+)
+
+// ScaleEvent is reported through OnScale every time a worker is added or retired.
+type ScaleEvent struct {
+	Time   time.Time
+	From   int
+	To     int
+	Reason ScaleReason
+}
+
+type scaling struct {
+	min     int
+	max     int
+	depth   int
+	wait    time.Duration
+	idle    time.Duration
+	onScale func(ScaleEvent)
+}
+
+// grow starts one more worker if the pool is below its max. It never adds to
+// a pool whose workers have all exited, so wg.Add can't race the final wg.Wait.
+func (p *Pool[In, Out]) grow(reason ScaleReason) {
+	p.scaleMu.Lock()
+	from := int(p.size.Load())
+	if from == 0 || from >= p.scale.max || p.ctx.Err() != nil {
+		p.scaleMu.Unlock()
+		return
+	}
+	p.size.Add(1)
+	p.wg.Add(1)
+	p.workerID++
+	id := p.workerID
+	p.scaleMu.Unlock()
+
+	p.start(id)
+	p.report(from, from+1, reason)
+}
+
+// shrink retires an idle worker that grow had added.
+func (p *Pool[In, Out]) shrink() {
+	p.scaleMu.Lock()
+	from := int(p.size.Add(-1)) + 1
+	p.scaleMu.Unlock()
+	p.report(from, from-1, ReasonIdle)
+}
+
+// exit accounts for a worker leaving because the pool closed or was cancelled.
+func (p *Pool[In, Out]) exit() {
+	p.scaleMu.Lock()
+	p.size.Add(-1)
+	p.scaleMu.Unlock()
+}
+
+func (p *Pool[In, Out]) report(from, to int, reason ScaleReason) {
+	if p.scale.onScale != nil {
+		p.scale.onScale(ScaleEvent{Time: time.Now(), From: from, To: to, Reason: reason})
+	}
+}
@@ -0,0 +1,210 @@
+package pqueue
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrClosed is returned by Push after Close, and by Pop once the queue is closed and drained.
+var ErrClosed = errors.New("pqueue: queue closed")
+
+// Item is one queued value with its scheduling data.
+type Item[T any] struct {
+	Value    T
+	Priority int       // higher runs first
+	Deadline time.Time // zero means no deadline
+	Enqueued time.Time
+}
+
+// Queue is a blocking priority queue that any number of goroutines may Push to
+// and Pop from.
+//
+// Higher Priority is taken first; among equal priorities the earlier deadline
+// wins, then FIFO. With WithAging the queue's lifetime is cut into steps of d
+// and each step boundary an item has waited across counts as one extra
+// priority level, so a steady stream of urgent work can delay but never
+// starve low-priority items. Items pushed within the same step keep their
+// relative priorities, and equal ones still tie. Items whose deadline has passed by the time
+// they would be handed out are dropped and passed to OnExpire instead.
+//
+// Close works like closing a channel: Push starts failing, Pop keeps returning
+// what's left and then ErrClosed.
+type Queue[T any] struct {
+	aging    time.Duration
+	onExpire func(Item[T])
+	epoch    time.Time
+
+	mu     sync.Mutex
+	items  entries[T]
+	seq    uint64
+	closed bool
+	wake   chan struct{} // closed and replaced on every Push and on Close
+}
+
+// Option configures a Queue.
+type Option[T any] func(*Queue[T])
+
+// WithAging raises a waiting item's effective priority by one for every step
+// of d, counted from the queue's creation, that passes while it is queued.
+func WithAging[T any](d time.Duration) Option[T] {
+	return func(q *Queue[T]) { q.aging = d }
+}
+
+// OnExpire is called, outside the queue's lock, for each item dropped because its deadline passed.
+func OnExpire[T any](fn func(Item[T])) Option[T] {
+	return func(q *Queue[T]) { q.onExpire = fn }
+}
+
+// New returns an empty, open queue.
+func New[T any](opts ...Option[T]) *Queue[T] {
+	q := &Queue[T]{epoch: time.Now(), wake: make(chan struct{})}
+	for _, opt := range opts {
+		opt(q)
+	}
+	return q
+}
+
+// Push adds v with the given priority and deadline (zero for none).
+func (q *Queue[T]) Push(v T, priority int, deadline time.Time) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrClosed
+	}
+
+	now := time.Now()
+	e := &entry[T]{
+		item: Item[T]{Value: v, Priority: priority, Deadline: deadline, Enqueued: now},
+		rank: int64(priority),
+		seq:  q.seq,
+	}
+	// An item's effective priority is priority + (steps now - steps at push).
+	// Comparing two items, the steps now cancel out, so ranking on
+	// priority - steps at push orders the heap correctly forever.
+	if q.aging > 0 {
+		e.rank -= int64(now.Sub(q.epoch) / q.aging)
+	}
+	q.seq++
+	heap.Push(&q.items, e)
+	q.broadcast()
+	return nil
+}
+
+// Pop blocks until an item is available and returns the highest ranked one.
+// It returns ErrClosed once the queue is closed and empty, or ctx's error.
+func (q *Queue[T]) Pop(ctx context.Context) (Item[T], error) {
+	for {
+		it, ok, wake, err := q.next()
+		if ok || err != nil {
+			return it, err
+		}
+		select {
+		case <-wake:
+		case <-ctx.Done():
+			return Item[T]{}, ctx.Err()
+		}
+	}
+}
+
+// TryPop returns the highest ranked item without blocking.
+func (q *Queue[T]) TryPop() (Item[T], bool) {
+	it, ok, _, _ := q.next()
+	return it, ok
+}
+
+// Len reports how many items are queued, including ones that may turn out expired.
+func (q *Queue[T]) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.items)
+}
+
+// Close stops new pushes and wakes blocked Pop calls. It is safe to call more than once.
+func (q *Queue[T]) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.closed {
+		q.closed = true
+		q.broadcast()
+	}
+}
+
+// next pops the best live item. If there is none it returns the channel to
+// wait on, or ErrClosed when nothing more will ever arrive.
+func (q *Queue[T]) next() (Item[T], bool, <-chan struct{}, error) {
+	var expired []Item[T]
+	defer func() {
+		if q.onExpire != nil {
+			for _, it := range expired {
+				q.onExpire(it)
+			}
+		}
+	}()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	now := time.Now()
+	for len(q.items) > 0 {
+		it := heap.Pop(&q.items).(*entry[T]).item
+		if !it.Deadline.IsZero() && now.After(it.Deadline) {
+			expired = append(expired, it)
+			continue
+		}
+		return it, true, nil, nil
+	}
+	if q.closed {
+		return Item[T]{}, false, nil, ErrClosed
+	}
+	return Item[T]{}, false, q.wake, nil
+}
+
+func (q *Queue[T]) broadcast() {
+	close(q.wake)
+	if !q.closed {
+		q.wake = make(chan struct{})
+	}
+}
+
+type entry[T any] struct {
+	item Item[T]
+	rank int64
+	seq  uint64
+}
+
+// entries implements heap.Interface.
+type entries[T any] []*entry[T]
+
+func (h entries[T]) Len() int { return len(h) }
+
+func (h entries[T]) Less(i, j int) bool {
+	a, b := h[i], h[j]
+	if a.rank != b.rank {
+		return a.rank > b.rank
+	}
+	if !a.item.Deadline.Equal(b.item.Deadline) {
+		switch {
+		case a.item.Deadline.IsZero():
+			return false
+		case b.item.Deadline.IsZero():
+			return true
+		}
+		return a.item.Deadline.Before(b.item.Deadline)
+	}
+	return a.seq < b.seq
+}
+
+func (h entries[T]) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *entries[T]) Push(x any) { *h = append(*h, x.(*entry[T])) }
+
+func (h *entries[T]) Pop() any {
+	old := *h
+	n := len(old)
+	e := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return e
+}
@@ -0,0 +1,132 @@
+package pqueue
+
+import (
+	"conc/leakcheck"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func popAll(t *testing.T, q *Queue[string]) []string {
+	t.Helper()
+	var got []string
+	for {
+		it, ok := q.TryPop()
+		if !ok {
+			return got
+		}
+		got = append(got, it.Value)
+	}
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestOrdering(t *testing.T) {
+	soon := time.Now().Add(time.Hour)
+	later := soon.Add(time.Hour)
+	for _, tc := range []struct {
+		name string
+		opts []Option[string]
+	}{
+		{"plain", nil},
+		// Everything is pushed within one aging step, so aging changes nothing.
+		{"aging", []Option[string]{WithAging[string](time.Hour)}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			q := New(tc.opts...)
+			q.Push("low", 0, time.Time{})
+			q.Push("no deadline", 1, time.Time{})
+			q.Push("later", 1, later)
+			q.Push("soon", 1, soon)
+			q.Push("soon too", 1, soon)
+			q.Push("high", 2, time.Time{})
+
+			want := []string{"high", "soon", "soon too", "later", "no deadline", "low"}
+			if got := popAll(t, q); !equal(got, want) {
+				t.Errorf("got %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestAgingPreventsStarvation(t *testing.T) {
+	const step = 20 * time.Millisecond
+	q := New(WithAging[string](step))
+	q.Push("old", 0, time.Time{})
+	time.Sleep(3 * step)
+	// Three steps later, "old" ranks like priority 3: above a fresh 2 but
+	// still below a fresh 5.
+	q.Push("urgent", 2, time.Time{})
+	q.Push("critical", 5, time.Time{})
+
+	want := []string{"critical", "old", "urgent"}
+	if got := popAll(t, q); !equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestExpiredItemsAreDropped(t *testing.T) {
+	var expired []string
+	q := New(OnExpire(func(it Item[string]) { expired = append(expired, it.Value) }))
+	q.Push("stale", 9, time.Now().Add(10*time.Millisecond))
+	q.Push("live", 0, time.Now().Add(time.Hour))
+	time.Sleep(20 * time.Millisecond)
+
+	if got := popAll(t, q); !equal(got, []string{"live"}) {
+		t.Errorf("got %q, want only the live item", got)
+	}
+	if !equal(expired, []string{"stale"}) {
+		t.Errorf("OnExpire saw %q, want the stale item", expired)
+	}
+}
+
+func TestPopBlocksUntilPushOrClose(t *testing.T) {
+	defer leakcheck.Take().Check(t)
+
+	q := New[string]()
+	got := make(chan string)
+	go func() {
+		for {
+			it, err := q.Pop(context.Background())
+			if err != nil {
+				close(got)
+				return
+			}
+			got <- it.Value
+		}
+	}()
+	time.Sleep(10 * time.Millisecond)
+	q.Push("a", 0, time.Time{})
+	if v := <-got; v != "a" {
+		t.Fatalf("Pop = %q, want a", v)
+	}
+	q.Close()
+	if _, ok := <-got; ok {
+		t.Fatal("Pop returned an item after Close on an empty queue")
+	}
+	if err := q.Push("b", 0, time.Time{}); !errors.Is(err, ErrClosed) {
+		t.Errorf("Push after Close = %v, want ErrClosed", err)
+	}
+}
+
+func TestPopHonoursContext(t *testing.T) {
+	defer leakcheck.Take().Check(t)
+
+	q := New[string]()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := q.Pop(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Pop = %v, want context.DeadlineExceeded", err)
+	}
+}
@@ -0,0 +1,259 @@
+// Package pubsub is an in-process publish/subscribe hub: one producer
+// publishes to a topic and every subscriber of that topic gets its own copy.
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	// ErrClosed is returned by Publish and Subscribe after Close, and is the
+	// Err of every subscription the hub closed on shutdown.
+	ErrClosed = errors.New("pubsub: hub closed")
+	// ErrSlowConsumer is the Err of a Disconnect subscription that was
+	// dropped because its buffer was full.
+	ErrSlowConsumer = errors.New("pubsub: subscriber too slow")
+)
+
+// Policy says what Publish does when a subscriber's buffer is full.
+type Policy int
+
+const (
+	// Block waits for the subscriber to make room (or for Publish's ctx).
+	// One stuck Block subscriber holds up the publisher for everyone.
+	Block Policy = iota
+	// Drop skips this message for the subscriber and counts it in Dropped.
+	Drop
+	// Disconnect unsubscribes the subscriber and closes its channel, with
+	// Err reporting ErrSlowConsumer.
+	Disconnect
+)
+
+func (p Policy) String() string {
+	switch p {
+	case Drop:
+		return "drop"
+	case Disconnect:
+		return "disconnect"
+	default:
+		return "block"
+	}
+}
+
+type subConfig struct {
+	buffer int
+	policy Policy
+}
+
+// SubOption configures a subscription.
+type SubOption func(*subConfig)
+
+// WithBuffer sets how many messages may queue for the subscriber (default 16).
+func WithBuffer(n int) SubOption {
+	return func(c *subConfig) { c.buffer = n }
+}
+
+// WithPolicy sets what happens when the subscriber's buffer is full (default Block).
+func WithPolicy(p Policy) SubOption {
+	return func(c *subConfig) { c.policy = p }
+}
+
+// Hub fans messages published on a topic out to that topic's subscribers.
+// Subscribers come and go at any time; each has its own buffer and slow-
+// consumer policy, so one lagging reader only affects the others if it chose
+// Block. Close shuts the hub down and closes every subscriber's channel.
+type Hub[T any] struct {
+	mu     sync.RWMutex
+	topics map[string][]*Subscription[T]
+	closed bool
+}
+
+// New returns an empty hub.
+func New[T any]() *Hub[T] {
+	return &Hub[T]{topics: make(map[string][]*Subscription[T])}
+}
+
+// Subscribe adds a subscriber to topic. Messages published from now on arrive
+// on the subscription's C until it is unsubscribed, disconnected or the hub
+// is closed, at which point C is closed.
+func (h *Hub[T]) Subscribe(topic string, opts ...SubOption) (*Subscription[T], error) {
+	cfg := subConfig{buffer: 16, policy: Block}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.buffer < 0 {
+		cfg.buffer = 0
+	}
+	ch := make(chan T, cfg.buffer)
+	s := &Subscription[T]{C: ch, ch: ch, hub: h, topic: topic, policy: cfg.policy, done: make(chan struct{})}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil, ErrClosed
+	}
+	h.topics[topic] = append(h.topics[topic], s)
+	return s, nil
+}
+
+// Publish sends v to every current subscriber of topic and reports how many
+// received it. It returns ctx's error if ctx ends while waiting on a Block
+// subscriber; the subscribers served before that keep their copy.
+func (h *Hub[T]) Publish(ctx context.Context, topic string, v T) (int, error) {
+	h.mu.RLock()
+	if h.closed {
+		h.mu.RUnlock()
+		return 0, ErrClosed
+	}
+	subs := append([]*Subscription[T](nil), h.topics[topic]...)
+	h.mu.RUnlock()
+
+	delivered := 0
+	for _, s := range subs {
+		ok, err := s.deliver(ctx, v)
+		if err != nil {
+			return delivered, err
+		}
+		if ok {
+			delivered++
+		}
+	}
+	return delivered, nil
+}
+
+// Subscribers reports how many subscribers topic has.
+func (h *Hub[T]) Subscribers(topic string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.topics[topic])
+}
+
+// Close stops the hub: Publish and Subscribe fail with ErrClosed from now on
+// and every subscription's channel is closed. Calling it again does nothing.
+func (h *Hub[T]) Close() {
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return
+	}
+	h.closed = true
+	topics := h.topics
+	h.topics = nil
+	h.mu.Unlock()
+
+	for _, subs := range topics {
+		for _, s := range subs {
+			s.close(ErrClosed)
+		}
+	}
+}
+
+// remove takes s off its topic's list; it reports false if s was already gone.
+func (h *Hub[T]) remove(s *Subscription[T]) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	subs := h.topics[s.topic]
+	for i, x := range subs {
+		if x == s {
+			h.topics[s.topic] = append(subs[:i:i], subs[i+1:]...)
+			if len(h.topics[s.topic]) == 0 {
+				delete(h.topics, s.topic)
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// Subscription is one subscriber's view of a topic.
+type Subscription[T any] struct {
+	// C delivers the topic's messages. It is closed exactly once, when the
+	// subscription ends for whatever reason; Err then says why.
+	C <-chan T
+
+	ch     chan T
+	hub    *Hub[T]
+	topic  string
+	policy Policy
+
+	// Publishers hold mu for reading while they send on ch; close takes it
+	// for writing after closing done, so ch is never closed under a sender.
+	mu        sync.RWMutex
+	done      chan struct{}
+	closeOnce sync.Once
+	err       error
+	dropped   atomic.Uint64
+}
+
+// Topic returns the topic s is subscribed to.
+func (s *Subscription[T]) Topic() string { return s.topic }
+
+// Dropped reports how many messages were skipped because s's buffer was full.
+func (s *Subscription[T]) Dropped() uint64 { return s.dropped.Load() }
+
+// Err reports why C was closed: nil after Unsubscribe, ErrSlowConsumer after
+// a Disconnect, ErrClosed after the hub shut down. It is nil while s is live.
+func (s *Subscription[T]) Err() error {
+	select {
+	case <-s.done:
+		return s.err
+	default:
+		return nil
+	}
+}
+
+// Unsubscribe removes s from the hub and closes C. Messages already buffered
+// are still readable. It is safe to call more than once.
+func (s *Subscription[T]) Unsubscribe() {
+	s.hub.remove(s)
+	s.close(nil)
+}
+
+func (s *Subscription[T]) deliver(ctx context.Context, v T) (bool, error) {
+	s.mu.RLock()
+	select {
+	case <-s.done:
+		s.mu.RUnlock()
+		return false, nil
+	default:
+	}
+
+	switch s.policy {
+	case Drop, Disconnect:
+		select {
+		case s.ch <- v:
+			s.mu.RUnlock()
+			return true, nil
+		default:
+		}
+		s.mu.RUnlock()
+		s.dropped.Add(1)
+		if s.policy == Disconnect && s.hub.remove(s) {
+			s.close(ErrSlowConsumer)
+		}
+		return false, nil
+	default:
+		defer s.mu.RUnlock()
+		select {
+		case s.ch <- v:
+			return true, nil
+		case <-s.done:
+			return false, nil
+		case <-ctx.Done():
+			return false, context.Cause(ctx)
+		}
+	}
+}
+
+func (s *Subscription[T]) close(err error) {
+	s.closeOnce.Do(func() {
+		s.err = err
+		close(s.done) // wakes any publisher blocked on s
+		s.mu.Lock()
+		close(s.ch)
+		s.mu.Unlock()
+	})
+}
@@ -0,0 +1,72 @@
+package pubsub
+
+import (
+	"conc/leakcheck"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPublishFansOut(t *testing.T) {
+	defer leakcheck.Take().Check(t)
+
+	h := New[int]()
+	defer h.Close()
+	a, _ := h.Subscribe("t")
+	b, _ := h.Subscribe("t")
+	if n, err := h.Publish(context.Background(), "t", 7); n != 2 || err != nil {
+		t.Fatalf("Publish = %d, %v", n, err)
+	}
+	if v := <-a.C; v != 7 {
+		t.Fatalf("a got %d", v)
+	}
+	if v := <-b.C; v != 7 {
+		t.Fatalf("b got %d", v)
+	}
+}
+
+func TestSlowConsumerPolicies(t *testing.T) {
+	defer leakcheck.Take().Check(t)
+
+	h := New[int]()
+	defer h.Close()
+	drop, _ := h.Subscribe("t", WithBuffer(1), WithPolicy(Drop))
+	disc, _ := h.Subscribe("t", WithBuffer(1), WithPolicy(Disconnect))
+	for i := 0; i < 3; i++ {
+		h.Publish(context.Background(), "t", i)
+	}
+	if d := drop.Dropped(); d != 2 {
+		t.Fatalf("Drop subscriber dropped %d, want 2", d)
+	}
+	<-disc.C
+	if _, ok := <-disc.C; ok {
+		t.Fatal("Disconnect subscriber's channel still open")
+	}
+	if !errors.Is(disc.Err(), ErrSlowConsumer) {
+		t.Fatalf("Err = %v, want ErrSlowConsumer", disc.Err())
+	}
+}
+
+func TestCloseReleasesBlockedPublisher(t *testing.T) {
+	defer leakcheck.Take().Check(t)
+
+	h := New[int]()
+	s, _ := h.Subscribe("t", WithBuffer(0))
+	errc := make(chan error, 1)
+	go func() {
+		_, err := h.Publish(context.Background(), "t", 1)
+		errc <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	h.Close()
+	if err := <-errc; err != nil {
+		t.Fatalf("Publish = %v", err)
+	}
+	if _, ok := <-s.C; ok {
+		t.Fatal("channel still open after Close")
+	}
+	if !errors.Is(s.Err(), ErrClosed) {
+		t.Fatalf("Err = %v, want ErrClosed", s.Err())
+	}
+}
@@ -0,0 +1,85 @@
+// Package safego launches goroutines that can't take the process down with
+// them: a panic is recovered, turned into a *PanicError carrying the stack and
+// the goroutine's name, and passed to a handler (or returned, for Call).
+package safego
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime/debug"
+	"runtime/pprof"
+	"sync/atomic"
+)
+
+// PanicError is a recovered panic.
+type PanicError struct {
+	Name  string // the label given to Go or Call
+	Value any    // what was passed to panic
+	Stack []byte // stack of the panicking goroutine
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic in %s: %v\n%s", e.Name, e.Value, e.Stack)
+}
+
+// Unwrap exposes the panic value when it was an error, e.g. for errors.Is.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+func newPanicError(name string, v any) *PanicError {
+	return &PanicError{Name: name, Value: v, Stack: debug.Stack()}
+}
+
+var handler atomic.Pointer[func(*PanicError)]
+
+// SetHandler sets where recovered panics are sent, from Go and from Call.
+// Without one, Go prints its panics to stderr and Call only returns them.
+// h may be called from many goroutines at once.
+func SetHandler(h func(*PanicError)) {
+	handler.Store(&h)
+}
+
+// notify passes pe to the handler, reporting whether there was one.
+func notify(pe *PanicError) bool {
+	if h := handler.Load(); h != nil && *h != nil {
+		(*h)(pe)
+		return true
+	}
+	return false
+}
+
+// Go runs fn in a new goroutine. The goroutine carries a pprof label
+// goroutine=name, so it can be told apart in profiles and goroutine dumps
+// (debug=1), and fn gets a ctx carrying the same label for anything it
+// starts itself. If fn panics, the panic goes to the handler.
+func Go(ctx context.Context, name string, fn func(ctx context.Context)) {
+	go func() {
+		ctx := pprof.WithLabels(ctx, pprof.Labels("goroutine", name))
+		pprof.SetGoroutineLabels(ctx)
+		defer func() {
+			if r := recover(); r != nil {
+				if pe := newPanicError(name, r); !notify(pe) {
+					fmt.Fprintln(os.Stderr, pe)
+				}
+			}
+		}()
+		fn(ctx)
+	}()
+}
+
+// Call runs fn on the current goroutine and returns its error, or a
+// *PanicError if it panicked, which also goes to the handler if one is set.
+// Worker loops use it so one bad job fails instead of killing the worker.
+func Call(name string, fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			pe := newPanicError(name, r)
+			notify(pe)
+			err = pe
+		}
+	}()
+	return fn()
+}
@@ -0,0 +1,82 @@
+package safego
+
+import (
+	"bytes"
+	"conc/leakcheck"
+	"context"
+	"errors"
+	"runtime/pprof"
+	"strings"
+	"testing"
+)
+
+var errBoom = errors.New("boom")
+
+// catch installs a handler that forwards panics to the returned channel, and
+// removes it when the test ends.
+func catch(t *testing.T) <-chan *PanicError {
+	panics := make(chan *PanicError, 1)
+	SetHandler(func(pe *PanicError) { panics <- pe })
+	t.Cleanup(func() { SetHandler(nil) })
+	return panics
+}
+
+func TestGoRecoversPanic(t *testing.T) {
+	defer leakcheck.Take().Check(t)
+	panics := catch(t)
+
+	Go(context.Background(), "crasher", func(context.Context) { panic(errBoom) })
+	pe := <-panics
+	if pe.Name != "crasher" || !errors.Is(pe, errBoom) {
+		t.Errorf("handler got %q with %v, want crasher with errBoom", pe.Name, pe.Value)
+	}
+	if !strings.Contains(string(pe.Stack), "safego_test.go") {
+		t.Errorf("stack doesn't show the panicking function:\n%s", pe.Stack)
+	}
+}
+
+func TestCall(t *testing.T) {
+	panics := catch(t)
+
+	err := Call("job", func() error { panic("oops") })
+	var pe *PanicError
+	if !errors.As(err, &pe) || pe.Name != "job" || pe.Value != "oops" {
+		t.Fatalf("Call = %v, want a *PanicError for job", err)
+	}
+	if got := <-panics; got != pe {
+		t.Errorf("handler got %v, want the returned error", got)
+	}
+
+	if err := Call("job", func() error { return errBoom }); err != errBoom {
+		t.Errorf("Call = %v, want errBoom passed through", err)
+	}
+	select {
+	case pe := <-panics:
+		t.Errorf("handler called for a plain error: %v", pe)
+	default:
+	}
+}
+
+func TestGoLabels(t *testing.T) {
+	defer leakcheck.Take().Check(t)
+
+	label := make(chan string)
+	release := make(chan struct{})
+	Go(context.Background(), "labelled", func(ctx context.Context) {
+		v, _ := pprof.Label(ctx, "goroutine")
+		label <- v
+		<-release
+	})
+	if v := <-label; v != "labelled" {
+		t.Errorf("ctx label = %q, want labelled", v)
+	}
+
+	var buf bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&buf, 1); err != nil {
+		t.Fatal(err)
+	}
+	close(release)
+	if !strings.Contains(buf.String(), `labels: {"goroutine":"labelled"}`) {
+		t.Errorf("goroutine profile has no labelled goroutine:\n%s", buf.String())
+	}
+}
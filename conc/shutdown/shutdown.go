@@ -0,0 +1,246 @@
+// Package shutdown turns SIGINT/SIGTERM into an orderly stop: a root context
+// is cancelled, registered components drain in reverse order of registration
+// within a grace period, and if any of them won't stop the process is forced
+// to exit with a report naming them.
+package shutdown
+
+import (
+	"conc/safego"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+var (
+	// ErrSignal is the cause of a shutdown started by a signal; the error
+	// wrapping it names the signal.
+	ErrSignal = errors.New("shutdown: signal received")
+	// ErrStuck is a component's Err when it didn't return within the grace
+	// period, or was never asked to because the period was already over.
+	ErrStuck = errors.New("shutdown: did not stop in time")
+)
+
+// Option configures a Coordinator.
+type Option func(*Coordinator)
+
+// WithGrace sets how long all components together get to stop (default 10s).
+func WithGrace(d time.Duration) Option {
+	return func(c *Coordinator) { c.grace = d }
+}
+
+// WithSignals sets the signals that start a shutdown (default SIGINT and SIGTERM).
+func WithSignals(sigs ...os.Signal) Option {
+	return func(c *Coordinator) { c.signals = sigs }
+}
+
+// WithOutput sets where the report of a forced exit is written (default stderr).
+func WithOutput(w io.Writer) Option {
+	return func(c *Coordinator) { c.out = w }
+}
+
+// WithExit replaces os.Exit for the forced exit.
+func WithExit(fn func(code int)) Option {
+	return func(c *Coordinator) { c.exit = fn }
+}
+
+// Coordinator owns a program's shutdown. Create it first thing in main, hand
+// its Context to everything long-running and Register whatever needs to
+// drain. A shutdown starts on the first signal or Shutdown call, whichever
+// comes first; a second signal while draining forces the exit at once.
+type Coordinator struct {
+	grace   time.Duration
+	signals []os.Signal
+	out     io.Writer
+	exit    func(int)
+
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	sigCh  chan os.Signal
+	forced chan struct{}
+
+	mu         sync.Mutex
+	components []component
+
+	startOnce sync.Once
+	finished  chan struct{}
+	report    Report
+}
+
+type component struct {
+	name string
+	stop func(ctx context.Context) error
+}
+
+// New returns a Coordinator and starts listening for signals.
+func New(opts ...Option) *Coordinator {
+	c := &Coordinator{
+		grace:    10 * time.Second,
+		signals:  []os.Signal{os.Interrupt, syscall.SIGTERM},
+		out:      os.Stderr,
+		exit:     os.Exit,
+		sigCh:    make(chan os.Signal, 2),
+		forced:   make(chan struct{}),
+		finished: make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.ctx, c.cancel = context.WithCancelCause(context.Background())
+	signal.Notify(c.sigCh, c.signals...)
+	go c.watch()
+	return c
+}
+
+// Context is the root context. It is cancelled, with the shutdown's cause, as
+// soon as a shutdown starts.
+func (c *Coordinator) Context() context.Context { return c.ctx }
+
+// Register adds a component. On shutdown stop is called after the root
+// context is cancelled and after every component registered later has
+// stopped; its ctx expires when the grace period runs out.
+func (c *Coordinator) Register(name string, stop func(ctx context.Context) error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.components = append(c.components, component{name, stop})
+}
+
+// Shutdown starts a shutdown with cause (nil for a normal stop) unless one is
+// already under way, and waits for it like Wait.
+func (c *Coordinator) Shutdown(cause error) Report {
+	c.start(cause)
+	return c.Wait()
+}
+
+// Wait blocks until a shutdown has finished draining and returns its report.
+// It only returns if every component stopped in time; otherwise the report
+// is written out and the process exits with status 1.
+func (c *Coordinator) Wait() Report {
+	<-c.finished
+	return c.report
+}
+
+func (c *Coordinator) watch() {
+	select {
+	case sig := <-c.sigCh:
+		c.start(fmt.Errorf("%w: %v", ErrSignal, sig))
+	case <-c.finished:
+		return
+	}
+	select {
+	case <-c.sigCh:
+		close(c.forced)
+	case <-c.finished:
+	}
+}
+
+func (c *Coordinator) start(cause error) {
+	c.startOnce.Do(func() {
+		if cause == nil {
+			cause = context.Canceled
+		}
+		c.cancel(cause)
+		go c.drain(cause)
+	})
+}
+
+func (c *Coordinator) drain(cause error) {
+	defer signal.Stop(c.sigCh)
+	began := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), c.grace)
+	defer cancel()
+
+	c.mu.Lock()
+	components := append([]component(nil), c.components...)
+	c.mu.Unlock()
+
+	rep := Report{Cause: cause}
+	forced := false
+	for i := len(components) - 1; i >= 0; i-- {
+		comp := components[i]
+		res := Result{Name: comp.name}
+		if forced || ctx.Err() != nil {
+			res.Err = ErrStuck
+			rep.Components = append(rep.Components, res)
+			continue
+		}
+
+		t0 := time.Now()
+		done := make(chan error, 1)
+		go func() {
+			done <- safego.Call("shutdown "+comp.name, func() error { return comp.stop(ctx) })
+		}()
+		select {
+		case res.Err = <-done:
+		case <-ctx.Done():
+			res.Err = ErrStuck
+		case <-c.forced:
+			res.Err, forced = ErrStuck, true
+		}
+		res.Took = time.Since(t0)
+		rep.Components = append(rep.Components, res)
+	}
+	rep.Took = time.Since(began)
+	c.report = rep
+
+	if rep.Stuck() {
+		fmt.Fprint(c.out, rep.String())
+		c.exit(1)
+	}
+	close(c.finished)
+}
+
+// Result is how one component's stop went.
+type Result struct {
+	Name string
+	Err  error // nil, what stop returned, or ErrStuck
+	Took time.Duration
+}
+
+// Report describes a finished shutdown. Components are listed in the order
+// they were stopped.
+type Report struct {
+	Cause      error
+	Took       time.Duration
+	Components []Result
+}
+
+// Stuck reports whether any component failed to stop within the grace period.
+func (r Report) Stuck() bool {
+	for _, res := range r.Components {
+		if errors.Is(res.Err, ErrStuck) {
+			return true
+		}
+	}
+	return false
+}
+
+// Err joins the errors of the components that didn't stop cleanly.
+func (r Report) Err() error {
+	var errs []error
+	for _, res := range r.Components {
+		if res.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", res.Name, res.Err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r Report) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "shutdown (%v) took %v\n", r.Cause, r.Took.Round(time.Millisecond))
+	for _, res := range r.Components {
+		status := "stopped"
+		if res.Err != nil {
+			status = res.Err.Error()
+		}
+		fmt.Fprintf(&b, "  %-12s %-8v %s\n", res.Name, res.Took.Round(time.Millisecond), status)
+	}
+	return b.String()
+}
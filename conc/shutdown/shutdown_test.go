@@ -0,0 +1,60 @@
+package shutdown
+
+import (
+	"bytes"
+	"conc/leakcheck"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestShutdownStopsInReverse(t *testing.T) {
+	defer leakcheck.Take().Check(t)
+
+	c := New(WithGrace(time.Second))
+	var order []string
+	for _, name := range []string{"db", "server"} {
+		name := name
+		c.Register(name, func(context.Context) error {
+			order = append(order, name)
+			return nil
+		})
+	}
+	rep := c.Shutdown(nil)
+	if c.Context().Err() == nil {
+		t.Fatal("root context not cancelled")
+	}
+	if strings.Join(order, ",") != "server,db" {
+		t.Fatalf("stop order %v", order)
+	}
+	if rep.Stuck() || rep.Err() != nil {
+		t.Fatalf("report %v", rep)
+	}
+}
+
+func TestStuckComponentForcesExit(t *testing.T) {
+	defer leakcheck.Take().Check(t)
+
+	var out bytes.Buffer
+	exited := make(chan int, 1)
+	release := make(chan struct{})
+	c := New(WithGrace(20*time.Millisecond), WithOutput(&out), WithExit(func(code int) { exited <- code }))
+	c.Register("slow", func(context.Context) error {
+		<-release
+		return nil
+	})
+	go c.Shutdown(errors.New("test"))
+
+	if code := <-exited; code != 1 {
+		t.Fatalf("exit code %d, want 1", code)
+	}
+	if !strings.Contains(out.String(), "slow") || !strings.Contains(out.String(), ErrStuck.Error()) {
+		t.Fatalf("report does not name the stuck component:\n%s", out.String())
+	}
+	close(release)
+	if rep := c.Wait(); !rep.Stuck() {
+		t.Fatal("report not marked stuck")
+	}
+}
@@ -0,0 +1,299 @@
+// Package supervisor runs long-lived goroutines and restarts them when they
+// fail, in the style of an Erlang/OTP supervisor.
+package supervisor
+
+import (
+	"conc/safego"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrTooManyRestarts is returned by Run when children failed more often than
+// the restart intensity allows.
+var ErrTooManyRestarts = errors.New("supervisor: too many restarts")
+
+// Strategy says which children are restarted when one of them fails.
+type Strategy int
+
+const (
+	// OneForOne restarts only the child that failed.
+	OneForOne Strategy = iota
+	// OneForAll stops every other child and restarts them all.
+	OneForAll
+	// RestForOne restarts the failed child and every child added after it,
+	// for when later children depend on earlier ones.
+	RestForOne
+)
+
+func (s Strategy) String() string {
+	switch s {
+	case OneForAll:
+		return "one-for-all"
+	case RestForOne:
+		return "rest-for-one"
+	default:
+		return "one-for-one"
+	}
+}
+
+// Restart says when a child that returned is started again.
+type Restart int
+
+const (
+	// Permanent children are always restarted, even after returning nil.
+	Permanent Restart = iota
+	// Transient children are restarted only after an error or a panic.
+	Transient
+	// Temporary children are never restarted.
+	Temporary
+)
+
+// EventKind is what happened to a child.
+type EventKind int
+
+const (
+	Started   EventKind = iota // the child's goroutine was launched
+	Exited                     // the child returned (or panicked) on its own; Err says how
+	Stopped                    // the supervisor cancelled the child and it returned
+	Restarted                  // the child was launched again after an exit
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case Exited:
+		return "exited"
+	case Stopped:
+		return "stopped"
+	case Restarted:
+		return "restarted"
+	default:
+		return "started"
+	}
+}
+
+// Event is passed to the OnEvent callback.
+type Event struct {
+	Child    string
+	Kind     EventKind
+	Err      error // for Exited: what the child returned, or a *safego.PanicError
+	Restarts int   // restarts of this child so far
+}
+
+// Option configures a Supervisor.
+type Option func(*Supervisor)
+
+// WithStrategy sets the restart strategy (default OneForOne).
+func WithStrategy(s Strategy) Option {
+	return func(sv *Supervisor) { sv.strategy = s }
+}
+
+// WithIntensity allows at most n restarts within any window of length d
+// (default 3 in 5s). One more and Run stops every child and gives up.
+func WithIntensity(n int, d time.Duration) Option {
+	return func(sv *Supervisor) { sv.maxRestarts, sv.window = n, d }
+}
+
+// OnEvent is called from Run's goroutine for every child lifecycle event.
+func OnEvent(fn func(Event)) Option {
+	return func(sv *Supervisor) { sv.onEvent = fn }
+}
+
+// ChildOption configures one child.
+type ChildOption func(*child)
+
+// WithRestart sets the child's restart policy (default Permanent).
+func WithRestart(r Restart) ChildOption {
+	return func(c *child) { c.restart = r }
+}
+
+// Supervisor owns a fixed list of children. Add them, then call Run.
+type Supervisor struct {
+	strategy    Strategy
+	maxRestarts int
+	window      time.Duration
+	onEvent     func(Event)
+
+	mu       sync.Mutex
+	children []*child
+	running  bool
+}
+
+type child struct {
+	name     string
+	run      func(ctx context.Context) error
+	restart  Restart
+	restarts int
+
+	// Set while an instance is running.
+	gen    int
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+type exit struct {
+	c   *child
+	gen int
+	err error
+}
+
+// New returns a Supervisor with no children.
+func New(opts ...Option) *Supervisor {
+	s := &Supervisor{maxRestarts: 3, window: 5 * time.Second}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Add registers a child. run should return when its ctx is done. Children
+// start in the order they were added and stop in reverse. Add panics if Run
+// has already started.
+func (s *Supervisor) Add(name string, run func(ctx context.Context) error, opts ...ChildOption) {
+	c := &child{name: name, run: run}
+	for _, opt := range opts {
+		opt(c)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		panic("supervisor: Add after Run")
+	}
+	s.children = append(s.children, c)
+}
+
+// Run starts every child and supervises them until ctx is done, when it stops
+// them in reverse order and returns nil. It also returns nil if every child
+// has exited for good (Transient children that succeeded, Temporary ones),
+// and ErrTooManyRestarts, wrapping the last failure, if the restart intensity
+// is exceeded.
+func (s *Supervisor) Run(ctx context.Context) error {
+	s.mu.Lock()
+	s.running = true
+	children := s.children
+	s.mu.Unlock()
+
+	exits := make(chan exit)
+	quit := make(chan struct{})
+	defer close(quit)
+
+	start := func(c *child, kind EventKind) {
+		cctx, cancel := context.WithCancel(ctx)
+		c.gen++
+		c.cancel, c.done = cancel, make(chan struct{})
+		gen, done := c.gen, c.done
+		s.emit(Event{Child: c.name, Kind: kind, Restarts: c.restarts})
+		go func() {
+			err := safego.Call(c.name, func() error { return c.run(cctx) })
+			close(done)
+			select {
+			case exits <- exit{c: c, gen: gen, err: err}:
+			case <-quit:
+			}
+		}()
+	}
+	// stop cancels the running children among cs, last first, and waits for each.
+	stop := func(cs []*child) {
+		for i := len(cs) - 1; i >= 0; i-- {
+			c := cs[i]
+			if c.done == nil {
+				continue
+			}
+			c.cancel()
+			<-c.done
+			c.cancel, c.done = nil, nil
+			s.emit(Event{Child: c.name, Kind: Stopped, Restarts: c.restarts})
+		}
+	}
+
+	for _, c := range children {
+		start(c, Started)
+	}
+
+	var restarts []time.Time
+	for {
+		if !anyRunning(children) {
+			return nil
+		}
+		var ex exit
+		select {
+		case <-ctx.Done():
+			stop(children)
+			return nil
+		case ex = <-exits:
+		}
+		c := ex.c
+		if ex.gen != c.gen || c.done == nil {
+			continue // an instance the supervisor stopped itself
+		}
+		c.cancel()
+		c.cancel, c.done = nil, nil
+		s.emit(Event{Child: c.name, Kind: Exited, Err: ex.err, Restarts: c.restarts})
+
+		if c.restart == Temporary || (c.restart == Transient && ex.err == nil) {
+			continue
+		}
+		if ctx.Err() != nil {
+			stop(children)
+			return nil
+		}
+
+		now := time.Now()
+		restarts = append(restarts, now)
+		for len(restarts) > 0 && now.Sub(restarts[0]) > s.window {
+			restarts = restarts[1:]
+		}
+		if len(restarts) > s.maxRestarts {
+			stop(children)
+			last := ex.err
+			if last == nil {
+				last = errors.New("returned without error")
+			}
+			return fmt.Errorf("%w: %d in %v, last %s: %w", ErrTooManyRestarts, len(restarts), s.window, c.name, last)
+		}
+
+		// Which children go down with c and come back up, in start order.
+		var group []*child
+		switch s.strategy {
+		case OneForAll:
+			group = children
+		case RestForOne:
+			for i, x := range children {
+				if x == c {
+					group = children[i:]
+					break
+				}
+			}
+		default:
+			group = []*child{c}
+		}
+		var again []*child
+		for _, x := range group {
+			if x == c || x.done != nil {
+				again = append(again, x)
+			}
+		}
+		stop(group)
+		for _, x := range again {
+			x.restarts++
+			start(x, Restarted)
+		}
+	}
+}
+
+func (s *Supervisor) emit(e Event) {
+	if s.onEvent != nil {
+		s.onEvent(e)
+	}
+}
+
+func anyRunning(children []*child) bool {
+	for _, c := range children {
+		if c.done != nil {
+			return true
+		}
+	}
+	return false
+}
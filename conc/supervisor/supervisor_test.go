@@ -0,0 +1,53 @@
+package supervisor
+
+import (
+	"conc/leakcheck"
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRestartsUntilCancelled(t *testing.T) {
+	defer leakcheck.Take().Check(t)
+
+	var runs atomic.Int32
+	s := New(WithIntensity(100, time.Second))
+	s.Add("flaky", func(ctx context.Context) error {
+		if runs.Add(1) < 3 {
+			return errors.New("boom")
+		}
+		<-ctx.Done()
+		return nil
+	})
+	s.Add("steady", func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() { errc <- s.Run(ctx) }()
+	for runs.Load() < 3 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-errc; err != nil {
+		t.Fatalf("Run = %v", err)
+	}
+}
+
+func TestTooManyRestarts(t *testing.T) {
+	defer leakcheck.Take().Check(t)
+
+	s := New(WithStrategy(OneForAll), WithIntensity(2, time.Second))
+	s.Add("panics", func(context.Context) error { panic("boom") })
+	s.Add("steady", func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+	if err := s.Run(context.Background()); !errors.Is(err, ErrTooManyRestarts) {
+		t.Fatalf("Run = %v, want ErrTooManyRestarts", err)
+	}
+}
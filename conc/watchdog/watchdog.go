@@ -0,0 +1,535 @@
+// Package watchdog spots goroutines that are stuck for good while the rest of
+// the program keeps running, which the runtime's "all goroutines are asleep"
+// check never sees.
+//
+// Only operations made through the package's Chan and Mutex are tracked. A
+// goroutine blocked in one of them for longer than the threshold is reported,
+// and so is any cycle of goroutines each waiting for another one in it. A
+// Lock waits for the mutex's holder; a Send or Recv waits for the goroutines
+// that have used the other end of the Chan before, and only counts as part
+// of a cycle if every one of them is stuck for good too. A channel partner
+// the watchdog has never seen (one that hasn't touched the Chan yet) is
+// invisible to it, so such cycles show up as stuck goroutines rather than as
+// a cycle. Reports carry every involved goroutine's stack with a note on
+// what it is waiting for.
+//
+// Every Chan operation and Mutex.Lock reads the goroutine's ID from a stack
+// trace, so this is a debugging aid rather than something for hot paths.
+package watchdog
+
+import (
+	"conc/leakcheck"
+	"conc/safego"
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Op is the kind of operation a goroutine is blocked in.
+type Op int
+
+const (
+	Send Op = iota // sending on a Chan
+	Recv           // receiving from a Chan
+	Lock           // locking a Mutex
+)
+
+func (o Op) String() string {
+	switch o {
+	case Recv:
+		return "chan receive"
+	case Lock:
+		return "lock"
+	default:
+		return "chan send"
+	}
+}
+
+// Wait is one goroutine blocked in an instrumented operation.
+type Wait struct {
+	Goroutine int
+	Op        Op
+	Object    string        // the Chan's or Mutex's name
+	Blocked   time.Duration // how long it had been blocked at report time
+	Holder    int           // for Lock: the goroutine holding the mutex (0 if unknown)
+	Note      string        // what, as far as the watchdog can tell, it is waiting for
+	Stack     string        // the goroutine's current stack
+}
+
+// Report is what the watchdog found in one scan.
+type Report struct {
+	Stuck  []Wait   // blocked longer than the threshold
+	Cycles [][]Wait // wait cycles: each goroutine waits for the next, the last for the first
+}
+
+func (r Report) String() string {
+	var b strings.Builder
+	for _, cycle := range r.Cycles {
+		ids := make([]string, 0, len(cycle)+1)
+		for _, w := range cycle {
+			ids = append(ids, strconv.Itoa(w.Goroutine))
+		}
+		ids = append(ids, ids[0])
+		fmt.Fprintf(&b, "watchdog: wait cycle %s\n", strings.Join(ids, " -> "))
+		for _, w := range cycle {
+			writeWait(&b, w)
+		}
+	}
+	for _, w := range r.Stuck {
+		fmt.Fprintf(&b, "watchdog: goroutine %d blocked for %v\n", w.Goroutine, w.Blocked.Round(time.Millisecond))
+		writeWait(&b, w)
+	}
+	return b.String()
+}
+
+func writeWait(b *strings.Builder, w Wait) {
+	fmt.Fprintf(b, "  goroutine %d: %s %q, %s\n", w.Goroutine, w.Op, w.Object, w.Note)
+	for _, line := range strings.Split(strings.TrimRight(w.Stack, "\n"), "\n") {
+		fmt.Fprintf(b, "    %s\n", line)
+	}
+}
+
+// Option configures a Watchdog.
+type Option func(*Watchdog)
+
+// WithThreshold sets how long a goroutine may stay blocked before it is
+// reported (default 2s).
+func WithThreshold(d time.Duration) Option {
+	return func(w *Watchdog) { w.threshold = d }
+}
+
+// WithInterval sets how often Run scans (default a quarter of the threshold).
+func WithInterval(d time.Duration) Option {
+	return func(w *Watchdog) { w.interval = d }
+}
+
+// OnDeadlock sets what happens with a report. The default writes it to
+// stderr. Each stuck wait and each cycle is reported once.
+func OnDeadlock(fn func(Report)) Option {
+	return func(w *Watchdog) { w.onDeadlock = fn }
+}
+
+// Watchdog keeps track of the goroutines blocked in its Chans and Mutexes.
+type Watchdog struct {
+	threshold  time.Duration
+	interval   time.Duration
+	onDeadlock func(Report)
+
+	mu       sync.Mutex
+	seq      uint64
+	waits    map[uint64]*wait
+	reported map[uint64]bool
+}
+
+type wait struct {
+	g     int
+	op    Op
+	name  string
+	since time.Time
+	mutex *Mutex // for Lock
+	users *users // for Send and Recv
+}
+
+// New returns a Watchdog; call Run to start scanning.
+func New(opts ...Option) *Watchdog {
+	w := &Watchdog{
+		threshold: 2 * time.Second,
+		onDeadlock: func(r Report) {
+			fmt.Fprint(os.Stderr, r.String())
+		},
+		waits:    make(map[uint64]*wait),
+		reported: make(map[uint64]bool),
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	if w.interval <= 0 {
+		w.interval = w.threshold / 4
+	}
+	return w
+}
+
+// Run scans for stuck goroutines every interval until ctx is done.
+func (w *Watchdog) Run(ctx context.Context) {
+	t := time.NewTicker(w.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			if r, ok := w.Scan(); ok {
+				w.onDeadlock(r)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Start runs Run in its own goroutine.
+func (w *Watchdog) Start(ctx context.Context) {
+	safego.Go(ctx, "watchdog", w.Run)
+}
+
+// Scan checks once and returns what it hasn't reported before; ok is false
+// when there is nothing new.
+func (w *Watchdog) Scan() (r Report, ok bool) {
+	now := time.Now()
+	w.mu.Lock()
+	byG := make(map[int]*wait, len(w.waits))
+	ids := make(map[*wait]uint64, len(w.waits))
+	for id, wt := range w.waits {
+		byG[wt.g] = wt
+		ids[wt] = id
+	}
+
+	var (
+		stuck  []*wait
+		cycles [][]*wait
+	)
+	dead := deadlocked(byG)
+	inCycle := make(map[*wait]bool)
+	for _, wt := range w.waits {
+		if inCycle[wt] || !dead[wt] {
+			continue
+		}
+		if cycle := findCycle(wt, byG, dead); cycle != nil {
+			for _, c := range cycle {
+				inCycle[c] = true
+			}
+			if !w.reported[ids[cycle[0]]] {
+				cycles = append(cycles, cycle)
+			}
+		}
+	}
+	for id, wt := range w.waits {
+		if !inCycle[wt] && !w.reported[id] && now.Sub(wt.since) >= w.threshold {
+			stuck = append(stuck, wt)
+		}
+	}
+	for _, cycle := range cycles {
+		for _, wt := range cycle {
+			w.reported[ids[wt]] = true
+		}
+	}
+	for _, wt := range stuck {
+		w.reported[ids[wt]] = true
+	}
+	notes := make(map[*wait]string)
+	for _, wt := range stuck {
+		notes[wt] = w.note(wt, byG)
+	}
+	for _, cycle := range cycles {
+		for _, wt := range cycle {
+			notes[wt] = w.note(wt, byG)
+		}
+	}
+	w.mu.Unlock()
+
+	if len(stuck) == 0 && len(cycles) == 0 {
+		return Report{}, false
+	}
+	stacks := make(map[int]string)
+	for _, g := range leakcheck.All() {
+		stacks[g.ID] = g.Stack
+	}
+	export := func(wt *wait) Wait {
+		x := Wait{Goroutine: wt.g, Op: wt.op, Object: wt.name, Blocked: now.Sub(wt.since), Note: notes[wt], Stack: stacks[wt.g]}
+		if wt.mutex != nil {
+			x.Holder = int(wt.mutex.owner.Load())
+		}
+		return x
+	}
+	sort.Slice(stuck, func(i, j int) bool { return stuck[i].since.Before(stuck[j].since) })
+	for _, wt := range stuck {
+		r.Stuck = append(r.Stuck, export(wt))
+	}
+	for _, cycle := range cycles {
+		var ws []Wait
+		for _, wt := range cycle {
+			ws = append(ws, export(wt))
+		}
+		r.Cycles = append(r.Cycles, ws)
+	}
+	return r, true
+}
+
+// deadlocked returns the waits that can never finish. A Lock waits for its
+// holder alone, but a Send or Recv is an OR-wait: any one of the goroutines
+// that used the other end may release it. So a wait can still make progress
+// if it is waiting for somebody not blocked here (or for nobody known), or
+// for a wait that can itself make progress; whatever is left after that
+// settles is deadlocked.
+func deadlocked(byG map[int]*wait) map[*wait]bool {
+	succ := make(map[*wait][]*wait, len(byG))
+	free := make(map[*wait]bool)
+	for _, wt := range byG {
+		s, blocked := next(wt, byG)
+		if !blocked {
+			free[wt] = true
+			continue
+		}
+		succ[wt] = s
+	}
+	for changed := true; changed; {
+		changed = false
+		for wt, s := range succ {
+			if free[wt] {
+				continue
+			}
+			for _, n := range s {
+				if free[n] {
+					free[wt] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+	dead := make(map[*wait]bool)
+	for _, wt := range byG {
+		if !free[wt] {
+			dead[wt] = true
+		}
+	}
+	return dead
+}
+
+// findCycle searches the wait-for graph (see next) among the deadlocked
+// waits for a path from wt back to itself and returns it, or nil. Every
+// successor of a deadlocked wait is deadlocked too, so the cycle holds no
+// matter which peer a channel wait would have been released by.
+func findCycle(wt *wait, byG map[int]*wait, dead map[*wait]bool) []*wait {
+	var (
+		path    []*wait
+		onPath  = make(map[*wait]bool)
+		cleared = make(map[*wait]bool) // no cycle back to wt through these
+	)
+	var visit func(cur *wait) bool
+	visit = func(cur *wait) bool {
+		if !dead[cur] {
+			return false
+		}
+		succ, _ := next(cur, byG)
+		path = append(path, cur)
+		onPath[cur] = true
+		for _, n := range succ {
+			if n == wt {
+				return true
+			}
+			if !onPath[n] && !cleared[n] && visit(n) {
+				return true
+			}
+		}
+		path = path[:len(path)-1]
+		delete(onPath, cur)
+		cleared[cur] = true
+		return false
+	}
+	if visit(wt) {
+		return path
+	}
+	return nil
+}
+
+// next returns the blocked goroutines wt is waiting for: a Lock's holder, or
+// everyone who has used the other end of a Chan. blocked is false if one of
+// them is not blocked in a tracked operation (or there is nobody), since it
+// may still release wt.
+func next(wt *wait, byG map[int]*wait) (succ []*wait, blocked bool) {
+	if wt.mutex != nil {
+		hw := byG[int(wt.mutex.owner.Load())]
+		return []*wait{hw}, hw != nil
+	}
+	peers := wt.users.other(wt.op, wt.g)
+	for _, g := range peers {
+		pw := byG[g]
+		if pw == nil {
+			return nil, false
+		}
+		succ = append(succ, pw)
+	}
+	return succ, len(succ) > 0
+}
+
+// note describes what wt is waiting for. Called with w.mu held.
+func (w *Watchdog) note(wt *wait, byG map[int]*wait) string {
+	if wt.op == Lock {
+		holder := int(wt.mutex.owner.Load())
+		if holder == 0 {
+			return "mutex is free again"
+		}
+		if hw := byG[holder]; hw != nil {
+			return fmt.Sprintf("held by goroutine %d, itself blocked in %s %q", holder, hw.op, hw.name)
+		}
+		return fmt.Sprintf("held by goroutine %d", holder)
+	}
+
+	peers := wt.users.other(wt.op, wt.g)
+	if len(peers) == 0 {
+		if wt.op == Send {
+			return "no tracked goroutine has received from it"
+		}
+		return "no tracked goroutine has sent on it"
+	}
+	var ds []string
+	for _, g := range peers {
+		if pw := byG[g]; pw != nil {
+			ds = append(ds, fmt.Sprintf("%d (blocked in %s %q)", g, pw.op, pw.name))
+		} else {
+			ds = append(ds, fmt.Sprintf("%d (not blocked here)", g))
+		}
+	}
+	side := "receivers"
+	if wt.op == Recv {
+		side = "senders"
+	}
+	return "known " + side + ": goroutine " + strings.Join(ds, ", ")
+}
+
+// begin records that goroutine g is about to block; the returned func clears it.
+func (w *Watchdog) begin(g int, op Op, name string, m *Mutex, u *users) func() {
+	w.mu.Lock()
+	w.seq++
+	id := w.seq
+	w.waits[id] = &wait{g: g, op: op, name: name, since: time.Now(), mutex: m, users: u}
+	w.mu.Unlock()
+	return func() {
+		w.mu.Lock()
+		delete(w.waits, id)
+		delete(w.reported, id)
+		w.mu.Unlock()
+	}
+}
+
+// users remembers which goroutines have sent on and received from a Chan.
+type users struct {
+	mu         sync.Mutex
+	send, recv map[int]bool
+}
+
+func (u *users) add(g int, op Op) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if op == Send {
+		u.send[g] = true
+	} else {
+		u.recv[g] = true
+	}
+}
+
+// other lists, in order, the goroutines other than self that used the
+// opposite end to op.
+func (u *users) other(op Op, self int) []int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	side := u.recv
+	if op == Recv {
+		side = u.send
+	}
+	var gs []int
+	for g := range side {
+		if g != self {
+			gs = append(gs, g)
+		}
+	}
+	sort.Ints(gs)
+	return gs
+}
+
+// Chan is a channel whose blocking sends and receives the watchdog tracks.
+type Chan[T any] struct {
+	w     *Watchdog
+	name  string
+	ch    chan T
+	users users
+}
+
+// NewChan makes a tracked channel with the given buffer size. name is how
+// reports refer to it.
+func NewChan[T any](w *Watchdog, name string, size int) *Chan[T] {
+	return &Chan[T]{w: w, name: name, ch: make(chan T, size), users: users{send: map[int]bool{}, recv: map[int]bool{}}}
+}
+
+// Send sends v, like c <- v.
+func (c *Chan[T]) Send(v T) {
+	g := goid()
+	c.users.add(g, Send)
+	select {
+	case c.ch <- v:
+		return
+	default:
+	}
+	done := c.w.begin(g, Send, c.name, nil, &c.users)
+	defer done()
+	c.ch <- v
+}
+
+// Recv receives a value, like v, ok := <-c.
+func (c *Chan[T]) Recv() (T, bool) {
+	g := goid()
+	c.users.add(g, Recv)
+	select {
+	case v, ok := <-c.ch:
+		return v, ok
+	default:
+	}
+	done := c.w.begin(g, Recv, c.name, nil, &c.users)
+	defer done()
+	v, ok := <-c.ch
+	return v, ok
+}
+
+// Close closes the channel.
+func (c *Chan[T]) Close() { close(c.ch) }
+
+// C exposes the channel itself, e.g. for select. Operations on it directly
+// are not tracked.
+func (c *Chan[T]) C() chan T { return c.ch }
+
+// Mutex is a sync.Mutex that records its holder, so the watchdog can tell
+// who a blocked Lock is waiting for.
+type Mutex struct {
+	w     *Watchdog
+	name  string
+	mu    sync.Mutex
+	owner atomic.Int64
+}
+
+// NewMutex makes a tracked mutex. name is how reports refer to it.
+func (w *Watchdog) NewMutex(name string) *Mutex {
+	return &Mutex{w: w, name: name}
+}
+
+// Lock locks m.
+func (m *Mutex) Lock() {
+	g := goid()
+	if !m.mu.TryLock() {
+		done := m.w.begin(g, Lock, m.name, m, nil)
+		m.mu.Lock()
+		done()
+	}
+	m.owner.Store(int64(g))
+}
+
+// Unlock unlocks m.
+func (m *Mutex) Unlock() {
+	m.owner.Store(0)
+	m.mu.Unlock()
+}
+
+// goid returns the calling goroutine's ID, parsed from "goroutine 7 [...".
+func goid() int {
+	var buf [64]byte
+	s := string(buf[:runtime.Stack(buf[:], false)])
+	s = strings.TrimPrefix(s, "goroutine ")
+	s, _, _ = strings.Cut(s, " ")
+	id, _ := strconv.Atoi(s)
+	return id
+}
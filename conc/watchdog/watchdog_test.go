@@ -0,0 +1,149 @@
+package watchdog
+
+import (
+	"conc/leakcheck"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestLockChannelCycle is a producer that sends while holding a lock and a
+// consumer that takes the same lock between receives: once the consumer is
+// waiting for the lock, the producer's send can never complete.
+func TestLockChannelCycle(t *testing.T) {
+	defer leakcheck.Take().Check(t)
+
+	w := New(WithThreshold(time.Hour))
+	mu := w.NewMutex("mu")
+	c := NewChan[int](w, "c", 0)
+	sent := make(chan struct{})
+
+	go func() { // producer
+		c.Send(0)
+		mu.Lock()
+		close(sent)
+		c.Send(1) // the consumer is stuck on mu
+		mu.Unlock()
+	}()
+	consumed := make(chan struct{})
+	go func() { // consumer
+		c.Recv()
+		<-sent
+		mu.Lock()
+		mu.Unlock()
+		close(consumed)
+	}()
+
+	var r Report
+	deadline := time.Now().Add(2 * time.Second)
+	for len(r.Cycles) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+		r, _ = w.Scan()
+	}
+	// Break the cycle from outside: an untracked receive lets the producer
+	// finish and unlock.
+	<-c.C()
+	<-consumed
+
+	if len(r.Cycles) != 1 || len(r.Cycles[0]) != 2 {
+		t.Fatalf("want one cycle of two goroutines, got:\n%v", r)
+	}
+	ops := map[Op]bool{}
+	for _, wt := range r.Cycles[0] {
+		ops[wt.Op] = true
+	}
+	if !ops[Send] || !ops[Lock] {
+		t.Errorf("cycle should be a send and a lock, got:\n%v", r)
+	}
+	if !strings.Contains(r.String(), "wait cycle") {
+		t.Errorf("report:\n%v", r)
+	}
+}
+
+// TestChannelWaitWithFreePeer checks that a send is not part of a cycle while
+// one of the channel's known receivers is still free to receive.
+func TestChannelWaitWithFreePeer(t *testing.T) {
+	defer leakcheck.Take().Check(t)
+
+	w := New(WithThreshold(10 * time.Millisecond))
+	c := NewChan[int](w, "c", 0)
+	go func() { c.Send(1) }()
+	c.Recv()
+
+	sent := make(chan struct{})
+	go func() {
+		c.Send(2)
+		close(sent)
+	}()
+	time.Sleep(30 * time.Millisecond)
+	r, ok := w.Scan()
+	if !ok || len(r.Cycles) != 0 || len(r.Stuck) != 1 {
+		t.Fatalf("want one stuck send and no cycle, got:\n%v", r)
+	}
+	if !strings.Contains(r.Stuck[0].Note, "not blocked here") {
+		t.Errorf("note %q should say the receiver is free", r.Stuck[0].Note)
+	}
+	c.Recv()
+	<-sent
+}
+
+// TestChannelWaitWithOneProgressingPeer has a receive holding mu while one of
+// its two known senders waits for mu and the other waits for a lock held by a
+// goroutine that will release it. The second sender can still unblock the
+// receive, so there is no cycle.
+func TestChannelWaitWithOneProgressingPeer(t *testing.T) {
+	defer leakcheck.Take().Check(t)
+
+	w := New(WithThreshold(time.Hour))
+	mu := w.NewMutex("mu")
+	m2 := w.NewMutex("m2")
+	c := NewChan[int](w, "c", 0)
+	m2.Lock()
+
+	locked := make(chan struct{})
+	done := make(chan struct{})
+	go func() { // G1
+		c.Recv()
+		c.Recv()
+		mu.Lock()
+		close(locked)
+		c.Recv() // from G3, once m2 is released
+		mu.Unlock()
+		c.Recv() // from G2
+		close(done)
+	}()
+	go func() { // G2
+		c.Send(0)
+		<-locked
+		mu.Lock()
+		c.Send(2)
+		mu.Unlock()
+	}()
+	go func() { // G3
+		c.Send(0)
+		m2.Lock()
+		c.Send(3)
+		m2.Unlock()
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		w.mu.Lock()
+		n := len(w.waits)
+		w.mu.Unlock()
+		if n == 3 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d goroutines blocked, want 3", n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	r, _ := w.Scan()
+	m2.Unlock()
+	<-done
+
+	if len(r.Cycles) != 0 {
+		t.Fatalf("want no cycle, got:\n%v", r)
+	}
+}
@@ -0,0 +1,216 @@
+package balancer
+
+import (
+	"conc/safego"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrClosed is returned by Do once the backend or dispatcher has been closed.
+var ErrClosed = errors.New("balancer: closed")
+
+// Handler serves one request on a backend.
+type Handler func(ctx context.Context, url string) (string, error)
+
+type request struct {
+	ctx   context.Context
+	url   string
+	reply chan<- Response
+	sent  time.Time
+}
+
+// Response is what a backend returned for one request.
+type Response struct {
+	Backend string
+	Data    string
+	Err     error
+	Latency time.Duration // queueing + service time
+}
+
+// Backend is a group of workers with its own queue, e.g. one server with a
+// number of request slots.
+type Backend struct {
+	Name    string
+	Workers int
+
+	queue       chan request
+	outstanding atomic.Int64 // queued + in service
+	served      atomic.Int64
+	wg          sync.WaitGroup
+	closeOnce   sync.Once
+}
+
+// NewBackend starts workers goroutines serving requests with handler.
+func NewBackend(name string, workers int, handler Handler) *Backend {
+	b := &Backend{Name: name, Workers: workers, queue: make(chan request, 4096)}
+	b.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		safego.Go(context.Background(), fmt.Sprintf("backend %s worker %d", name, i+1), func(context.Context) {
+			defer b.wg.Done()
+			for req := range b.queue {
+				var data string
+				err := safego.Call("backend "+b.Name, func() error {
+					var err error
+					data, err = handler(req.ctx, req.url)
+					return err
+				})
+				b.outstanding.Add(-1)
+				b.served.Add(1)
+				req.reply <- Response{Backend: b.Name, Data: data, Err: err, Latency: time.Since(req.sent)}
+			}
+		})
+	}
+	return b
+}
+
+// Outstanding reports requests queued or being served.
+func (b *Backend) Outstanding() int64 { return b.outstanding.Load() }
+
+// Served reports requests completed so far.
+func (b *Backend) Served() int64 { return b.served.Load() }
+
+// Close lets queued requests finish and waits for the workers to exit.
+func (b *Backend) Close() {
+	b.closeOnce.Do(func() { close(b.queue) })
+	b.wg.Wait()
+}
+
+// Strategy picks the backend for the next request.
+type Strategy interface {
+	Name() string
+	Pick(backends []*Backend) *Backend
+}
+
+// RoundRobin cycles through backends regardless of their load or speed.
+type RoundRobin struct{ next atomic.Uint64 }
+
+func (*RoundRobin) Name() string { return "round-robin" }
+
+func (r *RoundRobin) Pick(backends []*Backend) *Backend {
+	return backends[(r.next.Add(1)-1)%uint64(len(backends))]
+}
+
+// LeastOutstanding picks the backend with the fewest outstanding requests per
+// worker. It needs a look at every backend on each pick.
+type LeastOutstanding struct{}
+
+func (LeastOutstanding) Name() string { return "least-outstanding" }
+
+func (LeastOutstanding) Pick(backends []*Backend) *Backend {
+	best := backends[0]
+	for _, b := range backends[1:] {
+		if load(b) < load(best) {
+			best = b
+		}
+	}
+	return best
+}
+
+// PowerOfTwo samples two backends at random and takes the less loaded one:
+// nearly as good as LeastOutstanding while only looking at two backends.
+type PowerOfTwo struct {
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+func NewPowerOfTwo() *PowerOfTwo {
+	return &PowerOfTwo{rnd: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+func (*PowerOfTwo) Name() string { return "power-of-two" }
+
+func (p *PowerOfTwo) Pick(backends []*Backend) *Backend {
+	if len(backends) == 1 {
+		return backends[0]
+	}
+	p.mu.Lock()
+	i := p.rnd.Intn(len(backends))
+	j := p.rnd.Intn(len(backends) - 1)
+	p.mu.Unlock()
+	if j >= i {
+		j++
+	}
+	if load(backends[j]) < load(backends[i]) {
+		return backends[j]
+	}
+	return backends[i]
+}
+
+func load(b *Backend) float64 {
+	return float64(b.Outstanding()) / float64(b.Workers)
+}
+
+// Dispatcher sends each request to the backend its strategy picks.
+type Dispatcher struct {
+	strategy Strategy
+	backends []*Backend
+
+	// Do holds sendMu for reading while it queues a request; Close takes it
+	// for writing after closing done, so no backend queue is closed under a
+	// sender.
+	sendMu    sync.RWMutex
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// New returns a dispatcher over backends. It takes ownership of them: Close closes them.
+func New(strategy Strategy, backends ...*Backend) *Dispatcher {
+	return &Dispatcher{strategy: strategy, backends: backends, done: make(chan struct{})}
+}
+
+// Do runs url on a backend and waits for the response or ctx. It returns
+// ErrClosed once Close has been called, including while waiting for queue space.
+func (d *Dispatcher) Do(ctx context.Context, url string) Response {
+	reply := make(chan Response, 1) // the worker never blocks, even if we gave up
+	b, err := d.send(ctx, url, reply)
+	if err != nil {
+		return Response{Backend: b, Err: err}
+	}
+	select {
+	case resp := <-reply:
+		return resp
+	case <-ctx.Done():
+		return Response{Backend: b, Err: ctx.Err()}
+	}
+}
+
+// send queues the request on the picked backend and returns that backend's name.
+func (d *Dispatcher) send(ctx context.Context, url string, reply chan<- Response) (string, error) {
+	d.sendMu.RLock()
+	defer d.sendMu.RUnlock()
+	select {
+	case <-d.done:
+		return "", ErrClosed
+	default:
+	}
+	b := d.strategy.Pick(d.backends)
+	b.outstanding.Add(1)
+	select {
+	case b.queue <- request{ctx: ctx, url: url, reply: reply, sent: time.Now()}:
+		return b.Name, nil
+	case <-d.done:
+		b.outstanding.Add(-1)
+		return b.Name, ErrClosed
+	case <-ctx.Done():
+		b.outstanding.Add(-1)
+		return b.Name, ctx.Err()
+	}
+}
+
+// Close stops accepting requests and closes every backend once its queue
+// drains. It is safe to call concurrently with Do, and more than once.
+func (d *Dispatcher) Close() {
+	d.closeOnce.Do(func() {
+		close(d.done)
+		d.sendMu.Lock() // wait for senders already past the done check
+		d.sendMu.Unlock()
+	})
+	for _, b := range d.backends {
+		b.Close()
+	}
+}
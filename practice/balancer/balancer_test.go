@@ -0,0 +1,123 @@
+package balancer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"practice/balancer/simulate"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	requests   = 300
+	arrivalGap = 15 * time.Millisecond // ~66 req/s offered vs ~110 req/s total capacity
+)
+
+// TestCloseDuringDo closes the dispatcher while requests are still being sent,
+// some of them blocked on a full queue. None may panic, and each either gets
+// served or fails with ErrClosed.
+func TestCloseDuringDo(t *testing.T) {
+	release := make(chan struct{})
+	be := NewBackend("only", 1, func(ctx context.Context, url string) (string, error) {
+		<-release
+		return url, nil
+	})
+	d := New(&RoundRobin{}, be)
+
+	// More requests than the backend's queue holds, so the last ones block.
+	const n = 5000
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- d.Do(context.Background(), fmt.Sprint(i)).Err
+		}(i)
+	}
+	time.Sleep(50 * time.Millisecond)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+	}()
+	d.Close()
+	wg.Wait()
+	close(errs)
+
+	closed := 0
+	for err := range errs {
+		switch {
+		case errors.Is(err, ErrClosed):
+			closed++
+		case err != nil:
+			t.Errorf("Do = %v", err)
+		}
+	}
+	if closed == 0 {
+		t.Error("no request saw ErrClosed")
+	}
+	if resp := d.Do(context.Background(), "late"); !errors.Is(resp.Err, ErrClosed) {
+		t.Errorf("Do after Close = %v, want ErrClosed", resp.Err)
+	}
+}
+
+// BenchmarkStrategies replays the same open-loop arrival pattern, one request
+// every arrivalGap, against each strategy. Each op is a full replay of
+// requests requests; the interesting numbers are the latency percentiles and
+// each backend's share of the work, reported as extra metrics.
+func BenchmarkStrategies(b *testing.B) {
+	strategies := []func() Strategy{
+		func() Strategy { return &RoundRobin{} },
+		func() Strategy { return LeastOutstanding{} },
+		func() Strategy { return NewPowerOfTwo() },
+	}
+	for _, newStrategy := range strategies {
+		strategy := newStrategy()
+		b.Run(strategy.Name(), func(b *testing.B) {
+			var backends []*Backend
+			for _, s := range simulate.Servers {
+				backends = append(backends, NewBackend(s.Name, s.Workers, s.Fetch))
+			}
+			d := New(strategy, backends...)
+			defer d.Close()
+
+			var latencies []time.Duration
+			for n := 0; n < b.N; n++ {
+				latencies = append(latencies, replay(d)...)
+			}
+			b.StopTimer()
+
+			sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+			pct := func(p int) float64 {
+				return float64(latencies[(len(latencies)-1)*p/100]) / float64(time.Millisecond)
+			}
+			b.ReportMetric(pct(50), "p50-ms")
+			b.ReportMetric(pct(95), "p95-ms")
+			b.ReportMetric(pct(99), "p99-ms")
+			for _, be := range backends {
+				b.ReportMetric(float64(be.Served())/float64(len(latencies)), "share-"+be.Name)
+			}
+		})
+	}
+}
+
+func replay(d *Dispatcher) []time.Duration {
+	latencies := make([]time.Duration, requests)
+	var wg sync.WaitGroup
+	ticker := time.NewTicker(arrivalGap)
+	defer ticker.Stop()
+	for i := 0; i < requests; i++ {
+		<-ticker.C
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			resp := d.Do(context.Background(), fmt.Sprintf("https://example.com/%d", i))
+			latencies[i] = resp.Latency
+		}(i)
+	}
+	wg.Wait()
+	return latencies
+}
@@ -0,0 +1,40 @@
+// Package simulate has the simulated backends that challenge3 and the
+// balancer benchmarks run against, so both see the same servers.
+//
+// It doesn't import balancer, which lets balancer's own tests use it; a
+// Server's Fetch method has the balancer.Handler signature.
+package simulate
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// Server describes one simulated backend.
+type Server struct {
+	Name     string
+	Workers  int
+	Min, Max time.Duration // service time range
+}
+
+// Servers is a fast, a medium and a slow backend with two workers each,
+// about 110 requests/s of capacity between them.
+var Servers = []Server{
+	{"fast", 2, 20 * time.Millisecond, 40 * time.Millisecond},
+	{"medium", 2, 50 * time.Millisecond, 80 * time.Millisecond},
+	{"slow", 2, 150 * time.Millisecond, 300 * time.Millisecond},
+}
+
+// Fetch is the fetch simulation from the other problems with the server's
+// delay range. It gives up as soon as ctx is done.
+func (s Server) Fetch(ctx context.Context, url string) (string, error) {
+	delay := s.Min + time.Duration(rand.Int63n(int64(s.Max-s.Min)))
+	select {
+	case <-time.After(delay):
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+	return fmt.Sprintf("Data from %s (served by %s in %v)", url, s.Name, delay), nil
+}
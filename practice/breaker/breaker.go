@@ -0,0 +1,253 @@
+package breaker
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// ErrOpen is returned by Allow while a host's breaker is rejecting requests.
+var ErrOpen = errors.New("breaker: circuit open")
+
+// State is a breaker's position.
+type State int
+
+const (
+	Closed   State = iota // requests flow, outcomes are counted
+	Open                  // requests are rejected until OpenFor has passed
+	HalfOpen              // a few probe requests decide whether to close again
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("State(%d)", int(s))
+}
+
+// Event describes one state transition.
+type Event struct {
+	Host        string
+	From, To    State
+	FailureRate float64 // over the rolling window; only meaningful when leaving Closed
+	Time        time.Time
+}
+
+// Config controls when a breaker trips and how it recovers.
+type Config struct {
+	// Window is how far back outcomes count towards the failure rate.
+	Window time.Duration
+	// MinRequests is how many outcomes the window needs before it can trip,
+	// so one early failure doesn't open the circuit.
+	MinRequests int
+	// FailureRate (0-1) at or above which the breaker opens.
+	FailureRate float64
+	// OpenFor is how long an open breaker rejects before letting probes through.
+	OpenFor time.Duration
+	// Probes is how many half-open requests may run at once, and how many must
+	// succeed in a row to close the breaker.
+	Probes int
+	// OnStateChange, if set, is called for every transition, outside any lock.
+	OnStateChange func(Event)
+}
+
+type outcome struct {
+	at     time.Time
+	failed bool
+}
+
+// Breaker is a circuit breaker for a single host.
+type Breaker struct {
+	host string
+	cfg  Config
+
+	mu        sync.Mutex
+	state     State
+	outcomes  []outcome // inside Window, oldest first
+	openedAt  time.Time
+	gen       uint64 // bumped on every transition
+	probing   int    // half-open requests in flight
+	succeeded int    // half-open successes in a row
+}
+
+// Ticket is handed out by Allow for one admitted request and passed back to
+// Record with its outcome. It ties the outcome to the state the request was
+// admitted in, so a slow request let through before a transition can't
+// count against the next state.
+type Ticket struct {
+	gen uint64
+}
+
+// New returns a closed breaker for host.
+func New(host string, cfg Config) *Breaker {
+	if cfg.Probes < 1 {
+		cfg.Probes = 1
+	}
+	return &Breaker{host: host, cfg: cfg}
+}
+
+// State reports the current state, moving Open to HalfOpen if OpenFor has passed.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	ev := b.maybeHalfOpen(time.Now())
+	s := b.state
+	b.mu.Unlock()
+	b.emit(ev)
+	return s
+}
+
+// Allow reports whether a request may go ahead. Every allowed request must be
+// followed by exactly one Record call with the returned ticket and its outcome.
+func (b *Breaker) Allow() (Ticket, error) {
+	now := time.Now()
+	b.mu.Lock()
+	ev := b.maybeHalfOpen(now)
+	t := Ticket{gen: b.gen}
+	var err error
+	switch b.state {
+	case Open:
+		err = ErrOpen
+	case HalfOpen:
+		if b.probing >= b.cfg.Probes {
+			err = ErrOpen
+		} else {
+			b.probing++
+		}
+	}
+	b.mu.Unlock()
+	b.emit(ev)
+	return t, err
+}
+
+// Record reports the outcome of a request that Allow let through. Outcomes of
+// requests admitted before the breaker last changed state are ignored.
+func (b *Breaker) Record(t Ticket, failed bool) {
+	now := time.Now()
+	b.mu.Lock()
+	if t.gen != b.gen {
+		b.mu.Unlock()
+		return
+	}
+	var ev *Event
+	switch b.state {
+	case Closed:
+		b.outcomes = append(b.outcomes, outcome{at: now, failed: failed})
+		b.prune(now)
+		if rate, n := b.rate(); n >= b.cfg.MinRequests && rate >= b.cfg.FailureRate {
+			ev = b.to(Open, now)
+		}
+	case HalfOpen:
+		b.probing--
+		if failed {
+			ev = b.to(Open, now)
+			break
+		}
+		b.succeeded++
+		if b.succeeded >= b.cfg.Probes {
+			ev = b.to(Closed, now)
+		}
+	}
+	b.mu.Unlock()
+	b.emit(ev)
+}
+
+// maybeHalfOpen lets probes through once the open period is over. Callers hold b.mu.
+func (b *Breaker) maybeHalfOpen(now time.Time) *Event {
+	if b.state == Open && now.Sub(b.openedAt) >= b.cfg.OpenFor {
+		return b.to(HalfOpen, now)
+	}
+	return nil
+}
+
+// to switches state and resets what the new state counts. Callers hold b.mu.
+func (b *Breaker) to(s State, now time.Time) *Event {
+	rate, _ := b.rate()
+	ev := &Event{Host: b.host, From: b.state, To: s, FailureRate: rate, Time: now}
+	b.state = s
+	b.gen++
+	b.probing, b.succeeded = 0, 0
+	b.outcomes = nil
+	if s == Open {
+		b.openedAt = now
+	}
+	return ev
+}
+
+func (b *Breaker) prune(now time.Time) {
+	cut := 0
+	for cut < len(b.outcomes) && now.Sub(b.outcomes[cut].at) > b.cfg.Window {
+		cut++
+	}
+	b.outcomes = b.outcomes[cut:]
+}
+
+func (b *Breaker) rate() (float64, int) {
+	if len(b.outcomes) == 0 {
+		return 0, 0
+	}
+	failed := 0
+	for _, o := range b.outcomes {
+		if o.failed {
+			failed++
+		}
+	}
+	return float64(failed) / float64(len(b.outcomes)), len(b.outcomes)
+}
+
+func (b *Breaker) emit(ev *Event) {
+	if ev != nil && b.cfg.OnStateChange != nil {
+		b.cfg.OnStateChange(*ev)
+	}
+}
+
+// Group keeps one breaker per host, created on first use.
+type Group struct {
+	cfg Config
+
+	mu       sync.Mutex
+	breakers map[string]*Breaker
+}
+
+// NewGroup returns a Group whose breakers all use cfg.
+func NewGroup(cfg Config) *Group {
+	return &Group{cfg: cfg, breakers: map[string]*Breaker{}}
+}
+
+// For returns the breaker for rawURL's host.
+func (g *Group) For(rawURL string) *Breaker {
+	host := rawURL
+	if u, err := url.Parse(rawURL); err == nil && u.Host != "" {
+		host = u.Host
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	b, ok := g.breakers[host]
+	if !ok {
+		b = New(host, g.cfg)
+		g.breakers[host] = b
+	}
+	return b
+}
+
+// States returns every known host's current state.
+func (g *Group) States() map[string]State {
+	g.mu.Lock()
+	breakers := make([]*Breaker, 0, len(g.breakers))
+	for _, b := range g.breakers {
+		breakers = append(breakers, b)
+	}
+	g.mu.Unlock()
+
+	states := make(map[string]State, len(breakers))
+	for _, b := range breakers {
+		states[b.host] = b.State()
+	}
+	return states
+}
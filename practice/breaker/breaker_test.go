@@ -0,0 +1,153 @@
+package breaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStaleOutcomesIgnored(t *testing.T) {
+	b := New("example.com", Config{Window: time.Minute, MinRequests: 1, FailureRate: 0.5, OpenFor: time.Millisecond, Probes: 1})
+
+	// slow is admitted while closed and only finishes after the breaker has
+	// tripped and moved on to half-open.
+	slow, err := b.Allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fast, _ := b.Allow()
+	b.Record(fast, true)
+	if s := b.State(); s != Open {
+		t.Fatalf("state %v after a failure, want open", s)
+	}
+	time.Sleep(2 * time.Millisecond)
+
+	probe, err := b.Allow()
+	if err != nil {
+		t.Fatalf("probe rejected: %v", err)
+	}
+	if _, err := b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Fatalf("second probe = %v, want ErrOpen", err)
+	}
+
+	// The closed-state request's success must neither close the breaker nor
+	// free the probe slot.
+	b.Record(slow, false)
+	if s := b.State(); s != HalfOpen {
+		t.Fatalf("state %v after a stale outcome, want half-open", s)
+	}
+	if _, err := b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Fatalf("stale outcome freed a probe slot")
+	}
+
+	b.Record(probe, false)
+	if s := b.State(); s != Closed {
+		t.Fatalf("state %v after a successful probe, want closed", s)
+	}
+	if b.probing != 0 {
+		t.Fatalf("probing = %d, want 0", b.probing)
+	}
+}
+
+// allow is Allow for requests the test expects to be admitted.
+func allow(t *testing.T, b *Breaker) Ticket {
+	t.Helper()
+	tk, err := b.Allow()
+	if err != nil {
+		t.Fatalf("Allow = %v in state %v", err, b.State())
+	}
+	return tk
+}
+
+func TestMinRequests(t *testing.T) {
+	b := New("example.com", Config{Window: time.Minute, MinRequests: 4, FailureRate: 0.5, OpenFor: time.Minute})
+	for i := 0; i < 3; i++ {
+		b.Record(allow(t, b), true)
+	}
+	if s := b.State(); s != Closed {
+		t.Fatalf("state %v after 3 outcomes, want closed until MinRequests is reached", s)
+	}
+	b.Record(allow(t, b), false)
+	if s := b.State(); s != Open {
+		t.Fatalf("state %v at a 75%% failure rate over 4 outcomes, want open", s)
+	}
+	if _, err := b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Fatalf("Allow while open = %v, want ErrOpen", err)
+	}
+}
+
+func TestWindowForgetsOldOutcomes(t *testing.T) {
+	b := New("example.com", Config{Window: 20 * time.Millisecond, MinRequests: 2, FailureRate: 0.5, OpenFor: time.Minute})
+	b.Record(allow(t, b), true)
+	time.Sleep(30 * time.Millisecond)
+
+	// Had the old failure stayed in the window, this would make 1 of 2.
+	b.Record(allow(t, b), false)
+	if s := b.State(); s != Closed {
+		t.Fatalf("state %v, want closed once the old failure left the window", s)
+	}
+	b.Record(allow(t, b), true)
+	if s := b.State(); s != Open {
+		t.Fatalf("state %v after 1 of 2 recent outcomes failed, want open", s)
+	}
+}
+
+func TestHalfOpenProbeLimit(t *testing.T) {
+	b := New("example.com", Config{Window: time.Minute, MinRequests: 1, FailureRate: 0.5, OpenFor: time.Millisecond, Probes: 2})
+	b.Record(allow(t, b), true)
+	time.Sleep(2 * time.Millisecond)
+
+	p1, p2 := allow(t, b), allow(t, b)
+	if _, err := b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Fatalf("third concurrent probe = %v, want ErrOpen", err)
+	}
+	b.Record(p1, false)
+	if s := b.State(); s != HalfOpen {
+		t.Fatalf("state %v after one of two probes succeeded, want half-open", s)
+	}
+	// p1's slot is free again.
+	p3 := allow(t, b)
+	b.Record(p2, false)
+	if s := b.State(); s != Closed {
+		t.Fatalf("state %v after two probes succeeded in a row, want closed", s)
+	}
+	// p3 was admitted in the half-open state and no longer counts.
+	b.Record(p3, true)
+	if s := b.State(); s != Closed {
+		t.Fatalf("state %v after a stale probe failure, want closed", s)
+	}
+}
+
+func TestOnStateChange(t *testing.T) {
+	var events []Event
+	b := New("example.com", Config{
+		Window: time.Minute, MinRequests: 2, FailureRate: 0.5, OpenFor: time.Millisecond, Probes: 1,
+		OnStateChange: func(ev Event) { events = append(events, ev) },
+	})
+	b.Record(allow(t, b), false)
+	b.Record(allow(t, b), true) // 1 of 2: open
+	time.Sleep(2 * time.Millisecond)
+	b.Record(allow(t, b), true) // failed probe: open again
+	time.Sleep(2 * time.Millisecond)
+	b.Record(allow(t, b), false) // successful probe: closed
+
+	want := []struct{ from, to State }{
+		{Closed, Open}, {Open, HalfOpen}, {HalfOpen, Open}, {Open, HalfOpen}, {HalfOpen, Closed},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d: %+v", len(events), len(want), events)
+	}
+	for i, w := range want {
+		if ev := events[i]; ev.From != w.from || ev.To != w.to || ev.Host != "example.com" {
+			t.Errorf("event %d = %s %v -> %v, want %v -> %v", i, ev.Host, ev.From, ev.To, w.from, w.to)
+		}
+	}
+	if events[0].FailureRate != 0.5 {
+		t.Errorf("tripping event has failure rate %v, want 0.5", events[0].FailureRate)
+	}
+	for i := 1; i < len(events); i++ {
+		if events[i].Time.Before(events[i-1].Time) {
+			t.Errorf("event %d is timestamped before event %d", i, i-1)
+		}
+	}
+}
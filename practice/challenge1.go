@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"practice/pipeline"
+	"strings"
+	"time"
+)
+
+// Bonus Challenge 1: Pipeline Pattern
+// Goal: URLs go through multiple processing stages (fetch → parse → extract → store).
+// Each stage has its own worker count and output buffer; the report at the end
+// shows where items piled up.
+
+type Page struct {
+	URL  string
+	Body string
+}
+
+type Document struct {
+	URL   string
+	Title string
+	Words []string
+}
+
+type Record struct {
+	URL   string
+	Title string
+	Links int
+}
+
+// fetch simulates the network: 100-500ms, 15% failures
+func fetch(ctx context.Context, url string) (Page, error) {
+	delay := time.Duration(100+rand.Intn(400)) * time.Millisecond
+	select {
+	case <-time.After(delay):
+	case <-ctx.Done():
+		return Page{}, ctx.Err()
+	}
+	if rand.Float32() < 0.15 {
+		return Page{}, fmt.Errorf("network error for %s", url)
+	}
+	return Page{URL: url, Body: fmt.Sprintf("<title>%s</title> lorem ipsum https://a https://b", url)}, nil
+}
+
+// parse is cheap
+func parse(ctx context.Context, page Page) (Document, error) {
+	time.Sleep(10 * time.Millisecond)
+	title := strings.TrimSuffix(strings.TrimPrefix(strings.Fields(page.Body)[0], "<title>"), "</title>")
+	return Document{URL: page.URL, Title: title, Words: strings.Fields(page.Body)[1:]}, nil
+}
+
+// extract is the slow, single-worker stage in this demo
+func extract(ctx context.Context, doc Document) (Record, error) {
+	time.Sleep(80 * time.Millisecond)
+	links := 0
+	for _, w := range doc.Words {
+		if strings.HasPrefix(w, "https://") {
+			links++
+		}
+	}
+	if links == 0 {
+		return Record{}, pipeline.ErrSkip
+	}
+	return Record{URL: doc.URL, Title: doc.Title, Links: links}, nil
+}
+
+func main() {
+	// Seed random number generator
+	rand.Seed(time.Now().UnixNano())
+
+	var urls []string
+	for i := 1; i <= 40; i++ {
+		urls = append(urls, fmt.Sprintf("https://example.com/page/%d", i))
+	}
+
+	fmt.Println("Starting pipeline...")
+	start := time.Now()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	p := pipeline.New(ctx)
+
+	src := pipeline.Source(p, "source", urls)
+	pages := pipeline.Stage(p, "fetch", src, fetch, pipeline.Workers(8), pipeline.Buffer(8))
+	docs := pipeline.Stage(p, "parse", pages, parse, pipeline.Workers(2), pipeline.Buffer(4))
+	records := pipeline.Stage(p, "extract", docs, extract, pipeline.Workers(1))
+
+	stored := 0
+	pipeline.Sink(p, "store", records, func(ctx context.Context, r Record) error {
+		time.Sleep(5 * time.Millisecond)
+		stored++ // single worker, so no lock needed
+		return nil
+	})
+
+	if err := p.Wait(); err != nil {
+		fmt.Println("Pipeline stopped early:", err)
+	}
+	fmt.Printf("Stored %d records\n\n", stored)
+	pipeline.WriteReport(os.Stdout, p.Stats())
+
+	fmt.Printf("Total time: %v\n", time.Since(start))
+}
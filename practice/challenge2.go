@@ -0,0 +1,147 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/url"
+	"practice/breaker"
+	"sync"
+	"time"
+)
+
+// Bonus Challenge 2: Circuit Breaker
+// Goal: Stop making requests to a host once too many of them fail, and probe it
+// again later instead of hammering it.
+
+type FetchResult struct {
+	URL   string
+	Data  string
+	Error error
+}
+
+// failureRate per host: api.flaky.dev is down for the first second, then recovers.
+var downUntil = time.Now().Add(time.Second)
+
+func failureRate(host string) float32 {
+	if host == "api.flaky.dev" && time.Now().Before(downUntil) {
+		return 0.9
+	}
+	return 0.05
+}
+
+// fetchURLWithResult simulates fetching data and returns a result struct
+func fetchURLWithResult(rawURL string) FetchResult {
+	// Simulate network delay (50-150ms)
+	delay := time.Duration(50+rand.Intn(100)) * time.Millisecond
+	time.Sleep(delay)
+
+	u, _ := url.Parse(rawURL)
+	if rand.Float32() < failureRate(u.Host) {
+		return FetchResult{
+			URL:   rawURL,
+			Data:  "",
+			Error: fmt.Errorf("network error for %s", rawURL),
+		}
+	}
+
+	return FetchResult{
+		URL:   rawURL,
+		Data:  fmt.Sprintf("Data from %s (fetched in %v)", rawURL, delay),
+		Error: nil,
+	}
+}
+
+// fetchWithBreaker only calls fetchURLWithResult if the host's breaker allows
+// it, and feeds the outcome back into the breaker.
+func fetchWithBreaker(breakers *breaker.Group, rawURL string) FetchResult {
+	b := breakers.For(rawURL)
+	ticket, err := b.Allow()
+	if err != nil {
+		return FetchResult{URL: rawURL, Error: err}
+	}
+	result := fetchURLWithResult(rawURL)
+	b.Record(ticket, result.Error != nil)
+	return result
+}
+
+func main() {
+	// Seed random number generator
+	rand.Seed(time.Now().UnixNano())
+
+	hosts := []string{"example.com", "api.flaky.dev", "golang.org"}
+	var urls []string
+	for i := 1; i <= 30; i++ {
+		for _, host := range hosts {
+			urls = append(urls, fmt.Sprintf("https://%s/page/%d", host, i))
+		}
+	}
+
+	breakers := breaker.NewGroup(breaker.Config{
+		Window:      time.Second,
+		MinRequests: 4,
+		FailureRate: 0.5,
+		OpenFor:     300 * time.Millisecond,
+		Probes:      2,
+		OnStateChange: func(e breaker.Event) {
+			if e.From == breaker.Closed {
+				fmt.Printf("[breaker] %s: %s -> %s (failure rate %.0f%%)\n", e.Host, e.From, e.To, 100*e.FailureRate)
+				return
+			}
+			fmt.Printf("[breaker] %s: %s -> %s\n", e.Host, e.From, e.To)
+		},
+	})
+
+	fmt.Println("Starting fetch with per-host circuit breakers...")
+	start := time.Now()
+
+	jobs := make(chan string)
+	results := make(chan FetchResult)
+	wg := new(sync.WaitGroup)
+	for w := 0; w < 6; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for u := range jobs {
+				results <- fetchWithBreaker(breakers, u)
+			}
+		}()
+	}
+	go func() {
+		for _, u := range urls {
+			jobs <- u
+			time.Sleep(10 * time.Millisecond) // steady trickle of requests
+		}
+		close(jobs)
+	}()
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	type counts struct{ ok, failed, rejected int }
+	perHost := map[string]*counts{}
+	for result := range results {
+		u, _ := url.Parse(result.URL)
+		c, ok := perHost[u.Host]
+		if !ok {
+			c = &counts{}
+			perHost[u.Host] = c
+		}
+		switch {
+		case errors.Is(result.Error, breaker.ErrOpen):
+			c.rejected++
+		case result.Error != nil:
+			c.failed++
+		default:
+			c.ok++
+		}
+	}
+
+	for _, host := range hosts {
+		c := perHost[host]
+		fmt.Printf("%-14s ok %2d, failed %2d, rejected by breaker %2d\n", host, c.ok, c.failed, c.rejected)
+	}
+	fmt.Println("Final states:", breakers.States())
+	fmt.Printf("Total time: %v\n", time.Since(start))
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"practice/balancer"
+	"practice/balancer/simulate"
+	"sync"
+	"time"
+)
+
+// Bonus Challenge 3: Load Balancing
+// Goal: Distribute work across multiple "servers" with different response times
+// and capacities, and compare how each strategy does on tail latency.
+
+func main() {
+	// Seed random number generator
+	rand.Seed(time.Now().UnixNano())
+
+	// The strategies are compared on tail latency by BenchmarkStrategies:
+	//   go test -run '^$' -bench . ./balancer
+	// Here a handful of requests just shows power-of-two steering work away
+	// from the slow server.
+	var backends []*balancer.Backend
+	for _, s := range simulate.Servers {
+		backends = append(backends, balancer.NewBackend(s.Name, s.Workers, s.Fetch))
+	}
+	d := balancer.New(balancer.NewPowerOfTwo(), backends...)
+	defer d.Close()
+
+	start := time.Now()
+	wg := new(sync.WaitGroup)
+	for i := 0; i < 12; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			resp := d.Do(context.Background(), fmt.Sprintf("https://example.com/%d", i))
+			if resp.Err != nil {
+				fmt.Println("Error:", resp.Err)
+				return
+			}
+			fmt.Printf("%s (%v after sending)\n", resp.Data, resp.Latency.Round(time.Millisecond))
+		}(i)
+		time.Sleep(15 * time.Millisecond)
+	}
+	wg.Wait()
+
+	for _, b := range backends {
+		fmt.Printf("%s served %d\n", b.Name, b.Served())
+	}
+	fmt.Printf("Total time: %v\n", time.Since(start))
+}
@@ -0,0 +1,115 @@
+package fanout
+
+import (
+	"conc/chanx"
+	"conc/safego"
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Func is the work each fan-out worker does for one input.
+type Func[In, Out any] func(ctx context.Context, in In) (Out, error)
+
+// Result is one output on the merged stream, tagged with the worker (1..n)
+// that produced it.
+type Result[In, Out any] struct {
+	Worker int
+	In     In
+	Out    Out
+	Err    error
+}
+
+// Mode picks how Run reacts to errors.
+type Mode int
+
+const (
+	// CollectAll keeps going after failures and returns every error joined.
+	CollectAll Mode = iota
+	// FirstError cancels all workers on the first failure and returns it.
+	FirstError
+)
+
+// Distribute starts n workers that share src (fan-out). Each worker has its
+// own output channel, closed when src is drained or ctx is done.
+func Distribute[In, Out any](ctx context.Context, src <-chan In, n int, fn Func[In, Out]) []<-chan Result[In, Out] {
+	outs := make([]<-chan Result[In, Out], n)
+	for w := 1; w <= n; w++ {
+		out := make(chan Result[In, Out])
+		outs[w-1] = out
+		id := w
+		safego.Go(ctx, fmt.Sprintf("fanout worker %d", id), func(ctx context.Context) {
+			defer close(out)
+			for {
+				var in In
+				select {
+				case v, ok := <-src:
+					if !ok {
+						return
+					}
+					in = v
+				case <-ctx.Done():
+					return
+				}
+
+				r := Result[In, Out]{Worker: id, In: in}
+				r.Err = safego.Call(fmt.Sprintf("fanout worker %d", id), func() error {
+					var err error
+					r.Out, err = fn(ctx, in)
+					return err
+				})
+				select {
+				case out <- r:
+				case <-ctx.Done():
+					return
+				}
+			}
+		})
+	}
+	return outs
+}
+
+// Run fans inputs out to n workers running fn, fans their results back in and
+// returns them in completion order along with the error(s) per mode.
+func Run[In, Out any](ctx context.Context, inputs []In, n int, mode Mode, fn Func[In, Out]) ([]Result[In, Out], error) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	src := make(chan In)
+	go func() {
+		defer close(src)
+		for _, in := range inputs {
+			select {
+			case src <- in:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var (
+		results []Result[In, Out]
+		errs    []error
+	)
+	for r := range chanx.Merge(ctx, Distribute(ctx, src, n, fn)...) {
+		results = append(results, r)
+		if r.Err == nil {
+			continue
+		}
+		errs = append(errs, r.Err)
+		if mode == FirstError && len(errs) == 1 {
+			cancel(r.Err)
+		}
+	}
+
+	if mode == FirstError {
+		if len(errs) > 0 {
+			return results, errs[0]
+		}
+		return results, context.Cause(ctx)
+	}
+	if err := context.Cause(ctx); err != nil {
+		errs = append(errs, err) // the caller gave up before every input ran
+	}
+	return results, errors.Join(errs...)
+}
@@ -0,0 +1,121 @@
+package fanout
+
+import (
+	"conc/leakcheck"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+)
+
+var errBoom = errors.New("boom")
+
+func double(_ context.Context, n int) (int, error) {
+	if n%10 == 9 {
+		return 0, fmt.Errorf("input %d: %w", n, errBoom)
+	}
+	return 2 * n, nil
+}
+
+func TestRunCollectsEveryResult(t *testing.T) {
+	defer leakcheck.Take().Check(t)
+
+	inputs := make([]int, 50)
+	for i := range inputs {
+		inputs[i] = i
+	}
+	results, err := Run(context.Background(), inputs, 4, CollectAll, double)
+	if !errors.Is(err, errBoom) {
+		t.Fatalf("Run = %v, want the joined failures", err)
+	}
+	if n := len(err.(interface{ Unwrap() []error }).Unwrap()); n != 5 {
+		t.Errorf("got %d errors, want 5", n)
+	}
+
+	var seen []int
+	for _, r := range results {
+		if r.Worker < 1 || r.Worker > 4 {
+			t.Errorf("result from worker %d, want 1..4", r.Worker)
+		}
+		if r.Err == nil && r.Out != 2*r.In {
+			t.Errorf("input %d gave %d", r.In, r.Out)
+		}
+		seen = append(seen, r.In)
+	}
+	sort.Ints(seen)
+	if fmt.Sprint(seen) != fmt.Sprint(inputs) {
+		t.Errorf("results cover %v, want every input once", seen)
+	}
+}
+
+// blockUnlessZero fails input 0 and has every other input wait for ctx.
+func blockUnlessZero(ctx context.Context, n int) (int, error) {
+	if n == 0 {
+		return 0, errBoom
+	}
+	<-ctx.Done()
+	return 0, ctx.Err()
+}
+
+func TestRunFirstErrorCancelsWorkers(t *testing.T) {
+	defer leakcheck.Take().Check(t)
+
+	_, err := Run(context.Background(), []int{1, 2, 3, 0, 4, 5, 6}, 4, FirstError, blockUnlessZero)
+	if !errors.Is(err, errBoom) {
+		t.Fatalf("Run = %v, want errBoom", err)
+	}
+}
+
+func TestRunCancelledByCaller(t *testing.T) {
+	defer leakcheck.Take().Check(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	block := func(ctx context.Context, n int) (int, error) {
+		<-ctx.Done()
+		return 0, ctx.Err()
+	}
+	for _, mode := range []Mode{CollectAll, FirstError} {
+		if _, err := Run(ctx, []int{1, 2, 3, 4, 5, 6}, 3, mode, block); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("mode %d: Run = %v, want context.DeadlineExceeded", mode, err)
+		}
+	}
+}
+
+func TestDistributeClosesOutputs(t *testing.T) {
+	defer leakcheck.Take().Check(t)
+
+	src := make(chan int)
+	go func() {
+		defer close(src)
+		for i := 0; i < 20; i++ {
+			src <- i
+		}
+	}()
+	outs := Distribute(context.Background(), src, 3, double)
+	if len(outs) != 3 {
+		t.Fatalf("got %d output channels, want 3", len(outs))
+	}
+	done := make(chan int)
+	for w, out := range outs {
+		go func(w int, out <-chan Result[int, int]) {
+			n := 0
+			for r := range out {
+				if r.Worker != w+1 {
+					t.Errorf("worker %d's channel carried a result from worker %d", w+1, r.Worker)
+				}
+				n++
+			}
+			done <- n
+		}(w, out)
+	}
+	total := 0
+	for range outs {
+		total += <-done
+	}
+	if total != 20 {
+		t.Errorf("got %d results, want 20", total)
+	}
+}
@@ -0,0 +1,205 @@
+// Package frontier deduplicates URLs for a crawler: Normalize folds trivially
+// different spellings of a URL together, and Frontier remembers which
+// normalized URLs it has already accepted, in an exact set that turns into a
+// Bloom filter once it grows large.
+package frontier
+
+import (
+	"hash/fnv"
+	"math"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Normalize canonicalizes a URL so that trivially different spellings of the
+// same page compare equal: lower-case scheme and host, no default port, no
+// fragment, no trailing slash (the root path is "/"), and query parameters
+// sorted by key then value. Escapes in the path are kept as written, so
+// "/a%2Fb" (one segment) stays distinct from "/a/b".
+func Normalize(raw string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return "", err
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	host := strings.ToLower(u.Hostname())
+	if port := u.Port(); port != "" && !(u.Scheme == "http" && port == "80") && !(u.Scheme == "https" && port == "443") {
+		host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		host = "[" + host + "]" // IPv6 literal
+	}
+	u.Host = host
+	u.Fragment, u.RawFragment = "", ""
+
+	// Trim the escaped form, so an encoded slash at the end isn't mistaken
+	// for a trailing one, and keep it as RawPath.
+	path := strings.TrimRight(u.EscapedPath(), "/")
+	if path == "" {
+		path = "/"
+	}
+	if u.Path, err = url.PathUnescape(path); err != nil {
+		return "", err
+	}
+	u.RawPath = path
+
+	if u.RawQuery != "" {
+		q := u.Query()
+		keys := make([]string, 0, len(q))
+		for k := range q {
+			keys = append(keys, k)
+			sort.Strings(q[k])
+		}
+		sort.Strings(keys)
+		var b strings.Builder
+		for _, k := range keys {
+			for _, v := range q[k] {
+				if b.Len() > 0 {
+					b.WriteByte('&')
+				}
+				b.WriteString(url.QueryEscape(k))
+				b.WriteByte('=')
+				b.WriteString(url.QueryEscape(v))
+			}
+		}
+		u.RawQuery = b.String()
+	}
+	return u.String(), nil
+}
+
+// Stats reports what the frontier has done so far.
+type Stats struct {
+	Added   int  // new URLs accepted
+	Dropped int  // duplicates rejected (may include Bloom false positives)
+	Invalid int  // URLs that failed to parse
+	Bloom   bool // switched to the Bloom filter
+}
+
+// Frontier accepts each URL once. It is safe for concurrent use.
+//
+// It keeps an exact set until that holds maxExact URLs, then moves everything
+// into a Bloom filter sized for capacity URLs at the given false-positive
+// rate. From then on memory stays fixed, at the cost of occasionally dropping
+// a URL that was never seen.
+type Frontier struct {
+	maxExact int
+	capacity int
+	fpRate   float64
+
+	mu    sync.Mutex
+	exact map[string]struct{}
+	bloom *bloom
+	stats Stats
+}
+
+// New returns an empty frontier. maxExact <= 0 means never switch to the Bloom filter.
+func New(maxExact, capacity int, fpRate float64) *Frontier {
+	return &Frontier{maxExact: maxExact, capacity: capacity, fpRate: fpRate, exact: map[string]struct{}{}}
+}
+
+// Add reports whether raw is new, recording it if so.
+func (f *Frontier) Add(raw string) bool {
+	key, err := Normalize(raw)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err != nil {
+		f.stats.Invalid++
+		return false
+	}
+
+	if f.bloom != nil {
+		if !f.bloom.add(key) {
+			f.stats.Dropped++
+			return false
+		}
+		f.stats.Added++
+		return true
+	}
+
+	if _, ok := f.exact[key]; ok {
+		f.stats.Dropped++
+		return false
+	}
+	f.exact[key] = struct{}{}
+	f.stats.Added++
+	if f.maxExact > 0 && len(f.exact) >= f.maxExact {
+		f.switchToBloom()
+	}
+	return true
+}
+
+// Filter returns the URLs in urls that are new, in order.
+func (f *Frontier) Filter(urls []string) []string {
+	var fresh []string
+	for _, u := range urls {
+		if f.Add(u) {
+			fresh = append(fresh, u)
+		}
+	}
+	return fresh
+}
+
+// Stats returns a snapshot of the counters.
+func (f *Frontier) Stats() Stats {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.stats
+}
+
+func (f *Frontier) switchToBloom() {
+	capacity := f.capacity
+	if capacity < len(f.exact) {
+		capacity = 2 * len(f.exact)
+	}
+	f.bloom = newBloom(capacity, f.fpRate)
+	for key := range f.exact {
+		f.bloom.add(key)
+	}
+	f.exact = nil
+	f.stats.Bloom = true
+}
+
+// bloom is a fixed-size Bloom filter using double hashing over FNV-1a.
+type bloom struct {
+	bits []uint64
+	m    uint64
+	k    uint64
+}
+
+func newBloom(n int, p float64) *bloom {
+	if p <= 0 || p >= 1 {
+		p = 0.01
+	}
+	m := uint64(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	if m < 64 {
+		m = 64
+	}
+	k := uint64(math.Round(float64(m) / float64(n) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+	return &bloom{bits: make([]uint64, (m+63)/64), m: m, k: k}
+}
+
+// add sets key's bits and reports whether any of them was unset, i.e. whether
+// key is definitely new.
+func (b *bloom) add(key string) bool {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	sum := h.Sum64()
+	h1, h2 := sum&0xffffffff, sum>>32|1
+
+	added := false
+	for i := uint64(0); i < b.k; i++ {
+		bit := (h1 + i*h2) % b.m
+		word, mask := bit/64, uint64(1)<<(bit%64)
+		if b.bits[word]&mask == 0 {
+			b.bits[word] |= mask
+			added = true
+		}
+	}
+	return added
+}
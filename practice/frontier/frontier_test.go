@@ -0,0 +1,70 @@
+package frontier
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"HTTPS://Example.COM:443/a/b/?b=2&a=1#frag", "https://example.com/a/b?a=1&b=2"},
+		{"http://example.com", "http://example.com/"},
+		{"http://example.com:8080/", "http://example.com:8080/"},
+		{"http://example.com/a%2Fb", "http://example.com/a%2Fb"},
+		{"http://example.com/a%2F", "http://example.com/a%2F"},
+		{"http://example.com/a%20b/", "http://example.com/a%20b"},
+		{"http://[::1]:8080/x", "http://[::1]:8080/x"},
+		{"http://[::1]:80/x", "http://[::1]/x"},
+		{"http://[FE80::1]/", "http://[fe80::1]/"},
+	}
+	for _, tt := range tests {
+		got, err := Normalize(tt.in)
+		if err != nil {
+			t.Errorf("Normalize(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFrontierDropsSpellings(t *testing.T) {
+	f := New(10, 1000, 0.01)
+	for _, u := range []string{"https://a.example/x", "https://A.example/x/", "https://a.example/x#top", "https://a.example/x%2F"} {
+		f.Add(u)
+	}
+	if st := f.Stats(); st.Added != 2 || st.Dropped != 2 {
+		t.Fatalf("stats = %+v, want 2 added, 2 dropped", st)
+	}
+}
+
+func TestFrontierSwitchesToBloom(t *testing.T) {
+	f := New(5, 1000, 0.001)
+	var early []string
+	for i := 0; i < 5; i++ {
+		u := fmt.Sprintf("https://a.example/page/%d", i)
+		early = append(early, u)
+		if !f.Add(u) {
+			t.Fatalf("Add(%q) = false for a new URL", u)
+		}
+		if got, want := f.Stats().Bloom, i == 4; got != want {
+			t.Fatalf("after %d URLs Bloom = %v, want %v", i+1, got, want)
+		}
+	}
+
+	// URLs seen before the switch were carried over into the filter.
+	for _, u := range early {
+		if f.Add(u + "#again") {
+			t.Errorf("Add(%q) = true after the switch, want it rejected", u)
+		}
+	}
+	for i := 5; i < 100; i++ {
+		if u := fmt.Sprintf("https://a.example/page/%d", i); !f.Add(u) {
+			t.Errorf("Add(%q) = false for a new URL", u)
+		}
+	}
+	if st := f.Stats(); st.Added != 100 || st.Dropped != 5 || !st.Bloom {
+		t.Errorf("stats = %+v, want 100 added, 5 dropped, Bloom", st)
+	}
+}
@@ -0,0 +1,290 @@
+package pipeline
+
+import (
+	"conc/safego"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrSkip can be returned by a stage func to drop an item without counting it as an error.
+var ErrSkip = errors.New("pipeline: skip item")
+
+// Pipeline owns a set of stages connected by channels. Cancelling its
+// context (or a StopOnError stage failing) stops every stage; each closes its
+// output channel on the way out so downstream stages drain and exit too.
+type Pipeline struct {
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	start  time.Time
+	wg     sync.WaitGroup
+
+	mu     sync.Mutex
+	stages []*stats
+}
+
+// New returns an empty pipeline whose stages run under ctx.
+func New(ctx context.Context) *Pipeline {
+	p := &Pipeline{start: time.Now()}
+	p.ctx, p.cancel = context.WithCancelCause(ctx)
+	return p
+}
+
+// Context is cancelled when the pipeline stops early.
+func (p *Pipeline) Context() context.Context {
+	return p.ctx
+}
+
+// Stop cancels every stage with cause.
+func (p *Pipeline) Stop(cause error) {
+	p.cancel(cause)
+}
+
+// Wait blocks until every stage has exited and returns why the pipeline
+// stopped early, or nil if it ran to completion.
+func (p *Pipeline) Wait() error {
+	p.wg.Wait()
+	err := context.Cause(p.ctx)
+	p.cancel(nil)
+	return err
+}
+
+type stageConfig struct {
+	workers     int
+	buffer      int
+	stopOnError bool
+}
+
+// StageOption configures one stage.
+type StageOption func(*stageConfig)
+
+// Workers sets how many goroutines run the stage (default 1).
+func Workers(n int) StageOption {
+	return func(c *stageConfig) { c.workers = n }
+}
+
+// Buffer sets the capacity of the stage's output channel (default 0).
+func Buffer(n int) StageOption {
+	return func(c *stageConfig) { c.buffer = n }
+}
+
+// StopOnError stops the whole pipeline on the stage's first error instead of
+// dropping the failed item and carrying on.
+func StopOnError() StageOption {
+	return func(c *stageConfig) { c.stopOnError = true }
+}
+
+// Source emits items into the pipeline as its first stage.
+func Source[T any](p *Pipeline, name string, items []T, opts ...StageOption) <-chan T {
+	in := make(chan T)
+	go func() {
+		defer close(in)
+		for _, it := range items {
+			select {
+			case in <- it:
+			case <-p.ctx.Done():
+				return
+			}
+		}
+	}()
+	return Stage(p, name, in, func(_ context.Context, it T) (T, error) { return it, nil }, opts...)
+}
+
+// Stage starts a stage that reads in, applies fn to each item and sends the
+// result on the returned channel. Items for which fn fails are dropped.
+func Stage[In, Out any](p *Pipeline, name string, in <-chan In, fn func(context.Context, In) (Out, error), opts ...StageOption) <-chan Out {
+	cfg := stageConfig{workers: 1}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.workers < 1 {
+		cfg.workers = 1
+	}
+
+	st := p.register(name, cfg.workers)
+	out := make(chan Out, cfg.buffer)
+	wg := new(sync.WaitGroup)
+	wg.Add(cfg.workers)
+	for i := 0; i < cfg.workers; i++ {
+		go func() {
+			defer wg.Done()
+			for {
+				waitStart := time.Now()
+				var item In
+				select {
+				case v, ok := <-in:
+					if !ok {
+						return
+					}
+					item = v
+				case <-p.ctx.Done():
+					return
+				}
+				st.idle.Add(int64(time.Since(waitStart)))
+				st.in.Add(1)
+
+				busyStart := time.Now()
+				var res Out
+				err := safego.Call("stage "+name, func() error {
+					var err error
+					res, err = fn(p.ctx, item)
+					return err
+				})
+				st.busy.Add(int64(time.Since(busyStart)))
+				if errors.Is(err, ErrSkip) {
+					st.skipped.Add(1)
+					continue
+				}
+				if err != nil {
+					st.errors.Add(1)
+					if cfg.stopOnError {
+						p.cancel(fmt.Errorf("stage %s: %w", name, err))
+						return
+					}
+					continue
+				}
+
+				sendStart := time.Now()
+				select {
+				case out <- res:
+				case <-p.ctx.Done():
+					return
+				}
+				st.blocked.Add(int64(time.Since(sendStart)))
+				st.out.Add(1)
+			}
+		}()
+	}
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		wg.Wait()
+		st.finished.Store(int64(time.Since(p.start)))
+		close(out)
+	}()
+	return out
+}
+
+// Sink is a final stage that consumes items without producing any.
+func Sink[In any](p *Pipeline, name string, in <-chan In, fn func(context.Context, In) error, opts ...StageOption) {
+	out := Stage(p, name, in, func(ctx context.Context, item In) (struct{}, error) {
+		return struct{}{}, fn(ctx, item)
+	}, opts...)
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		for range out {
+		}
+	}()
+}
+
+// StageStats describes how a stage spent its time.
+//
+// Busy is time inside the stage func, Idle is time waiting for input
+// (starved by upstream) and Blocked is time waiting to hand results
+// downstream (backpressure), each summed over the stage's workers.
+type StageStats struct {
+	Name     string
+	Workers  int
+	In       int64
+	Out      int64
+	Errors   int64
+	Skipped  int64
+	Busy     time.Duration
+	Idle     time.Duration
+	Blocked  time.Duration
+	Elapsed  time.Duration
+	Finished bool
+}
+
+// Throughput is items emitted per second over the stage's lifetime.
+func (s StageStats) Throughput() float64 {
+	if s.Elapsed <= 0 {
+		return 0
+	}
+	return float64(s.Out) / s.Elapsed.Seconds()
+}
+
+// Utilization is the fraction of the stage's worker time spent doing work.
+func (s StageStats) Utilization() float64 {
+	total := float64(s.Elapsed) * float64(s.Workers)
+	if total <= 0 {
+		return 0
+	}
+	return float64(s.Busy) / total
+}
+
+type stats struct {
+	name                          string
+	workers                       int
+	in, out, errors, skipped      atomic.Int64
+	busy, idle, blocked, finished atomic.Int64
+}
+
+func (p *Pipeline) register(name string, workers int) *stats {
+	st := &stats{name: name, workers: workers}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stages = append(p.stages, st)
+	return st
+}
+
+// Stats returns a snapshot for every stage, in the order they were added.
+func (p *Pipeline) Stats() []StageStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Since(p.start)
+	out := make([]StageStats, 0, len(p.stages))
+	for _, st := range p.stages {
+		s := StageStats{
+			Name:    st.name,
+			Workers: st.workers,
+			In:      st.in.Load(),
+			Out:     st.out.Load(),
+			Errors:  st.errors.Load(),
+			Skipped: st.skipped.Load(),
+			Busy:    time.Duration(st.busy.Load()),
+			Idle:    time.Duration(st.idle.Load()),
+			Blocked: time.Duration(st.blocked.Load()),
+			Elapsed: now,
+		}
+		if f := st.finished.Load(); f > 0 {
+			s.Elapsed, s.Finished = time.Duration(f), true
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+// Bottleneck returns the stage with the highest utilization: the one whose
+// workers were busiest while the others waited on it.
+func Bottleneck(stats []StageStats) (StageStats, bool) {
+	var best StageStats
+	found := false
+	for _, s := range stats {
+		if !found || s.Utilization() > best.Utilization() {
+			best, found = s, true
+		}
+	}
+	return best, found
+}
+
+// WriteReport prints a per-stage table and names the bottleneck.
+func WriteReport(w io.Writer, stats []StageStats) {
+	fmt.Fprintf(w, "%-10s %7s %5s %5s %5s %8s %6s %9s %9s %9s\n",
+		"stage", "workers", "in", "out", "err", "items/s", "util", "busy", "idle", "blocked")
+	for _, s := range stats {
+		fmt.Fprintf(w, "%-10s %7d %5d %5d %5d %8.1f %5.0f%% %9v %9v %9v\n",
+			s.Name, s.Workers, s.In, s.Out, s.Errors, s.Throughput(), 100*s.Utilization(),
+			s.Busy.Round(time.Millisecond), s.Idle.Round(time.Millisecond), s.Blocked.Round(time.Millisecond))
+	}
+	if b, ok := Bottleneck(stats); ok {
+		fmt.Fprintf(w, "bottleneck: %s (%.0f%% busy; stages before it spend their time blocked, stages after it idle)\n",
+			b.Name, 100*b.Utilization())
+	}
+}
@@ -0,0 +1,131 @@
+package pipeline
+
+import (
+	"conc/leakcheck"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+)
+
+var errBoom = errors.New("boom")
+
+func numbers(n int) []int {
+	ns := make([]int, n)
+	for i := range ns {
+		ns[i] = i
+	}
+	return ns
+}
+
+func TestSingleWorkerStagesKeepOrder(t *testing.T) {
+	defer leakcheck.Take().Check(t)
+
+	p := New(context.Background())
+	src := Source(p, "source", numbers(100))
+	doubled := Stage(p, "double", src, func(_ context.Context, n int) (int, error) { return 2 * n, nil })
+	var got []int
+	Sink(p, "collect", doubled, func(_ context.Context, n int) error {
+		got = append(got, n)
+		return nil
+	})
+	if err := p.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	for i, n := range got {
+		if n != 2*i {
+			t.Fatalf("item %d is %d; order not kept", i, n)
+		}
+	}
+	if len(got) != 100 {
+		t.Fatalf("collected %d items, want 100", len(got))
+	}
+}
+
+func TestParallelStageDeliversEverything(t *testing.T) {
+	defer leakcheck.Take().Check(t)
+
+	p := New(context.Background())
+	src := Source(p, "source", numbers(100))
+	kept := Stage(p, "filter", src, func(_ context.Context, n int) (int, error) {
+		switch {
+		case n%10 == 0:
+			return 0, ErrSkip
+		case n%10 == 1:
+			return 0, errBoom
+		}
+		return n, nil
+	}, Workers(4), Buffer(4))
+	var (
+		mu  sync.Mutex
+		got []int
+	)
+	Sink(p, "collect", kept, func(_ context.Context, n int) error {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, n)
+		return nil
+	}, Workers(2))
+	if err := p.Wait(); err != nil {
+		t.Fatalf("Wait = %v; failed items should be dropped, not stop the pipeline", err)
+	}
+
+	var want []int
+	for n := 0; n < 100; n++ {
+		if n%10 > 1 {
+			want = append(want, n)
+		}
+	}
+	sort.Ints(got)
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("collected %v, want %v", got, want)
+	}
+	st := p.Stats()[1]
+	if st.In != 100 || st.Out != 80 || st.Errors != 10 || st.Skipped != 10 || !st.Finished {
+		t.Errorf("filter stats = %+v, want 100 in, 80 out, 10 errors, 10 skipped", st)
+	}
+}
+
+func TestStopOnErrorStopsEveryStage(t *testing.T) {
+	defer leakcheck.Take().Check(t)
+
+	p := New(context.Background())
+	src := Source(p, "source", numbers(1000))
+	checked := Stage(p, "check", src, func(_ context.Context, n int) (int, error) {
+		if n == 5 {
+			return 0, errBoom
+		}
+		return n, nil
+	}, StopOnError())
+	Sink(p, "discard", checked, func(context.Context, int) error { return nil })
+	err := p.Wait()
+	if !errors.Is(err, errBoom) {
+		t.Fatalf("Wait = %v, want errBoom", err)
+	}
+	if st := p.Stats()[0]; st.Out >= 1000 {
+		t.Errorf("source emitted all %d items after the failure", st.Out)
+	}
+}
+
+func TestStopCancelsBlockedStages(t *testing.T) {
+	defer leakcheck.Take().Check(t)
+
+	p := New(context.Background())
+	src := Source(p, "source", numbers(10))
+	started := make(chan struct{})
+	var once sync.Once
+	Sink(p, "stuck", src, func(ctx context.Context, _ int) error {
+		once.Do(func() { close(started) })
+		<-ctx.Done()
+		return ctx.Err()
+	}, Workers(3))
+
+	<-started
+	stop := errors.New("shutting down")
+	p.Stop(stop)
+	if err := p.Wait(); !errors.Is(err, stop) {
+		t.Fatalf("Wait = %v, want the Stop cause", err)
+	}
+}
@@ -1,112 +1,201 @@
-// package main
-
-// import (
-// 	"fmt"
-// 	"math/rand"
-// 	"time"
-// )
-
-// // Problem 5: Worker Pool Pattern (Medium-Hard)
-// // Goal: Implement a worker pool to process URLs with a fixed number of workers.
-
-// type FetchResult struct {
-// 	URL   string
-// 	Data  string
-// 	Error error
-// }
-
-// // fetchURLWithResult simulates fetching data and returns a result struct
-// func fetchURLWithResult(url string) FetchResult {
-// 	// Simulate network delay (100-500ms)
-// 	delay := time.Duration(100+rand.Intn(400)) * time.Millisecond
-// 	time.Sleep(delay)
-
-// 	// Simulate occasional failures (25% chance)
-// 	if rand.Float32() < 0.25 {
-// 		return FetchResult{
-// 			URL:   url,
-// 			Data:  "",
-// 			Error: fmt.Errorf("network error for %s", url),
-// 		}
-// 	}
-
-// 	return FetchResult{
-// 		URL:   url,
-// 		Data:  fmt.Sprintf("Data from %s (fetched in %v)", url, delay),
-// 		Error: nil,
-// 	}
-// }
-
-// func worker(jobs <-chan string, results chan<- FetchResult) {
-// 	for url := range jobs {
-// 		results <- fetchURLWithResult(url)
-// 	}
-// }
-
-// func main() {
-// 	// Seed random number generator
-// 	rand.Seed(time.Now().UnixNano())
-
-// 	// URLs to fetch
-// 	urls := []string{
-// 		"https://example.com",
-// 		"https://google.com",
-// 		"https://github.com",
-// 		"https://stackoverflow.com",
-// 		"https://golang.org",
-// 		"https://medium.com",
-// 		"https://dev.to",
-// 		"https://reddit.com",
-// 		"https://news.ycombinator.com",
-// 		"https://dev.to",
-// 		"https://stackoverflow.com",
-// 		"https://golang.org",
-// 		"https://medium.com",
-// 		"https://dev.to",
-// 		"https://reddit.com",
-// 	}
-
-// 	fmt.Println("Starting worker pool fetch...")
-// 	start := time.Now()
-
-// 	// TODO: Implement worker pool pattern
-// 	// 1. Create a pool of 3 workers
-// 	// 2. Use a job channel to distribute URLs to workers
-// 	// 3. Use a results channel to collect results
-// 	// 4. Workers should process jobs until the job channel is closed
-// 	// 5. Handle graceful shutdown of workers
-// 	// 6. Collect and print all results
-// 	// 7. Handle errors appropriately
-
-// 	// Your implementation goes here:
-
-// 	/*
-
-// 		While using unbuffered channels (jobs and results), the producer for loop (line90~92) trys to send 15 urls to channel jobs sequentially.
-// 		However, there are only 3 go routines for worker, meaning after the first 3 urls sent, all 3 go routines are blocked as the main go routines is still in the loop and has not reached to the consumer code (line 95~98) yet.
-// 		While using buffered channels, the producer for loop would be able to send all 15 urls to 3 channels as they have buffers with same capacity as the length of urls.
-// 		And line 93 close(jobs) won't cause any deadlock as we've done sending so it is OK to close that jobs channel, right? Then consumer consumes the messages without any problem.
-
-// 	*/
-// 	jobs := make(chan string, len(urls))
-// 	results := make(chan FetchResult, len(urls))
-
-// 	for i := 0; i < 3; i++ {
-// 		go worker(jobs, results)
-// 	}
-
-// 	for _, url := range urls {
-// 		jobs <- url
-// 	}
-// 	close(jobs)
-
-// 	for i := 0; i < len(urls); i++ {
-// 		result := <-results
-// 		fmt.Printf("URL: %s, Data: %s, Error: %v\n", result.URL, result.Data, result.Error)
-// 	}
-
-// 	close(results)
-
-// 	elapsed := time.Since(start)
-// 	fmt.Printf("Total time: %v\n", elapsed)
-// }
+package main
+
+import (
+	"conc/pool"
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"practice/frontier"
+	"practice/sink"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// Problem 5: Worker Pool Pattern (Medium-Hard)
+// Goal: Implement a worker pool to process URLs with a fixed number of workers.
+
+type FetchResult struct {
+	URL     string
+	Data    string
+	Error   error
+	Latency time.Duration
+}
+
+// fetchURLWithResult simulates fetching data and returns a result struct
+func fetchURLWithResult(url string) FetchResult {
+	// Simulate network delay (100-500ms)
+	delay := time.Duration(100+rand.Intn(400)) * time.Millisecond
+	time.Sleep(delay)
+
+	// Simulate occasional failures (25% chance)
+	if rand.Float32() < 0.25 {
+		return FetchResult{
+			URL:   url,
+			Data:  "",
+			Error: fmt.Errorf("network error for %s", url),
+		}
+	}
+
+	return FetchResult{
+		URL:   url,
+		Data:  fmt.Sprintf("Data from %s (fetched in %v)", url, delay),
+		Error: nil,
+	}
+}
+
+// worker fetches one URL and times it; the pool owns the goroutines and channels.
+func worker(ctx context.Context, url string) (FetchResult, error) {
+	start := time.Now()
+	result := fetchURLWithResult(url)
+	result.Latency = time.Since(start)
+	return result, result.Error
+}
+
+// openSink picks the sink from the output file's extension: .jsonl, .csv, or
+// .db/.sqlite for an SQLite database with a "results" table.
+func openSink(path string) (sink.Sink, error) {
+	switch filepath.Ext(path) {
+	case ".jsonl", ".csv":
+		f, err := os.Create(path)
+		if err != nil {
+			return nil, err
+		}
+		if filepath.Ext(path) == ".csv" {
+			return sink.NewCSV(f), nil
+		}
+		return sink.NewJSONL(f), nil
+	case ".db", ".sqlite":
+		db, err := sql.Open("sqlite", path)
+		if err != nil {
+			return nil, err
+		}
+		s, err := sink.NewSQLite(db, "results")
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+		return sqliteSink{s, db}, nil
+	}
+	return nil, fmt.Errorf("unknown output format %q, want .jsonl, .csv, .db or .sqlite", filepath.Ext(path))
+}
+
+// sqliteSink also closes the database the SQL sink writes to.
+type sqliteSink struct {
+	*sink.SQL
+	db *sql.DB
+}
+
+func (s sqliteSink) Close() error { return errors.Join(s.SQL.Close(), s.db.Close()) }
+
+func main() {
+	out := flag.String("out", "", "also write every result to this .jsonl, .csv or .db (SQLite) file")
+	flag.Parse()
+
+	// Results go to the file through a writer goroutine, so a slow disk never
+	// holds up the loop below (and with it the workers). A bad -out fails
+	// here, before any fetching starts.
+	var results *sink.Writer
+	if *out != "" {
+		s, err := openSink(*out)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		results = sink.NewWriter(s, 200*time.Millisecond)
+	}
+
+	// Seed random number generator
+	rand.Seed(time.Now().UnixNano())
+
+	// URLs to fetch
+	urls := []string{
+		"https://example.com",
+		"https://google.com",
+		"https://github.com",
+		"https://stackoverflow.com",
+		"https://golang.org",
+		"https://medium.com",
+		"https://dev.to",
+		"https://reddit.com",
+		"https://news.ycombinator.com",
+		"https://dev.to",
+		"https://stackoverflow.com",
+		"https://golang.org",
+		"https://medium.com",
+		"https://dev.to",
+		"https://reddit.com",
+	}
+
+	fmt.Println("Starting worker pool fetch...")
+	start := time.Now()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	/*
+		The producer goroutine below can't deadlock against the consumer loop: Submit only
+		waits for queue space, workers never wait on results, and Close/Wait mark the end of
+		the input and of the work without counting results by hand.
+	*/
+	// Each URL gets up to 3 attempts with 100ms/200ms backoff; whatever still
+	// fails lands in the dead-letter queue instead of just being printed.
+	fetcher := pool.New(ctx, worker,
+		pool.WithWorkers(3),
+		pool.WithQueueSize(len(urls)),
+		pool.WithRetry(pool.RetryPolicy{MaxAttempts: 3, Backoff: 100 * time.Millisecond}),
+	)
+
+	// The list repeats dev.to, reddit.com and others; the frontier drops repeats
+	// (after normalising case, trailing slashes and query order) before they
+	// cost a fetch. Past 10k URLs it switches to a Bloom filter to bound memory.
+	seen := frontier.New(10_000, 1_000_000, 0.001)
+
+	go func() {
+		defer fetcher.Close()
+		for _, url := range urls {
+			if !seen.Add(url) {
+				continue
+			}
+			if err := fetcher.Submit(ctx, url); err != nil {
+				fmt.Printf("Submit %s: %v\n", url, err)
+				return
+			}
+		}
+	}()
+
+	for r := range fetcher.Results() {
+		result := r.Out
+		fmt.Printf("URL: %s, Data: %s, Error: %v, Attempts: %d\n", result.URL, result.Data, result.Error, r.Attempts)
+		if results != nil {
+			results.Send(sink.NewRecord(r.In, result.Data, r.Err, result.Latency))
+		}
+	}
+	fetcher.Wait()
+
+	if results != nil {
+		// Close flushes whatever is still queued before we exit.
+		if err := results.Close(); err != nil {
+			fmt.Println("Writing results:", err)
+		}
+		fmt.Printf("Wrote %d results to %s\n", results.Written(), *out)
+	}
+
+	fmt.Printf("Fetched %d unique URLs, dropped %d duplicates\n", seen.Stats().Added, seen.Stats().Dropped)
+	stats := fetcher.Stats()
+	fmt.Printf("First try: %d, after retry: %d (%d retries), dead-lettered: %d, cancelled: %d\n",
+		stats.FirstTry, stats.AfterRetry, stats.Retries, stats.DeadLettered, stats.Cancelled)
+	if fetcher.DeadLetters().Len() > 0 {
+		// Saved like this, they can be loaded with ReadJSON and re-driven in a later run.
+		fmt.Println("Dead letters:")
+		fetcher.DeadLetters().WriteJSON(os.Stdout)
+	}
+
+	elapsed := time.Since(start)
+	fmt.Printf("Total time: %v\n", elapsed)
+}
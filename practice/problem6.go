@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"practice/fanout"
+	"time"
+)
+
+// Problem 6: Fan-in/Fan-out with Error Handling (Hard)
+// Goal: Implement fan-out (distribute work) and fan-in (collect results) patterns with comprehensive error handling.
+
+type FetchResult struct {
+	URL   string
+	Data  string
+	Error error
+}
+
+// fetchURLWithResult simulates fetching data, giving up when ctx is done
+func fetchURLWithResult(ctx context.Context, url string) FetchResult {
+	// Simulate network delay (100-500ms)
+	delay := time.Duration(100+rand.Intn(400)) * time.Millisecond
+	select {
+	case <-time.After(delay):
+	case <-ctx.Done():
+		return FetchResult{URL: url, Error: fmt.Errorf("fetch %s: %w", url, ctx.Err())}
+	}
+
+	// Simulate occasional failures (15% chance)
+	if rand.Float32() < 0.15 {
+		return FetchResult{
+			URL:   url,
+			Data:  "",
+			Error: fmt.Errorf("network error for %s", url),
+		}
+	}
+
+	return FetchResult{
+		URL:   url,
+		Data:  fmt.Sprintf("Data from %s (fetched in %v)", url, delay),
+		Error: nil,
+	}
+}
+
+// fetch is the fan-out worker: one request, with its own 400ms timeout.
+func fetch(ctx context.Context, url string) (FetchResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, 400*time.Millisecond)
+	defer cancel()
+	result := fetchURLWithResult(ctx, url)
+	return result, result.Error
+}
+
+func run(mode fanout.Mode, urls []string) {
+	start := time.Now()
+	results, err := fanout.Run(context.Background(), urls, 4, mode, fetch)
+	for _, r := range results {
+		fmt.Printf("Worker %d: URL: %s, Data: %s, Error: %v\n", r.Worker, r.In, r.Out.Data, r.Err)
+	}
+	fmt.Printf("Got %d/%d results, error: %v\n", len(results), len(urls), err)
+	fmt.Printf("Total time: %v\n", time.Since(start))
+}
+
+func main() {
+	// Seed random number generator
+	rand.Seed(time.Now().UnixNano())
+
+	// URLs to fetch
+	urls := []string{
+		"https://example.com",
+		"https://google.com",
+		"https://github.com",
+		"https://stackoverflow.com",
+		"https://golang.org",
+		"https://medium.com",
+		"https://dev.to",
+		"https://reddit.com",
+		"https://news.ycombinator.com",
+		"https://go.dev",
+	}
+
+	// Every URL runs and every failure (network error or 400ms timeout) is reported.
+	fmt.Println("Starting fan-out/fan-in fetch, collecting all errors...")
+	run(fanout.CollectAll, urls)
+
+	// The first failure cancels the other workers; their in-flight fetches stop early.
+	fmt.Println("\nStarting fan-out/fan-in fetch, stopping at the first error...")
+	run(fanout.FirstError, urls)
+}
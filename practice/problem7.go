@@ -0,0 +1,112 @@
+package main
+
+import (
+	"conc/leakcheck"
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"os/signal"
+	"practice/scrape"
+	"runtime"
+	"time"
+)
+
+// Problem 7: Context Cancellation (Hard)
+// Goal: Implement proper cancellation using context to stop all operations when needed.
+
+// fetchURLWithContext simulates fetching data, giving up as soon as ctx is done
+func fetchURLWithContext(ctx context.Context, url string) scrape.Result {
+	// Simulate network delay (100-500ms)
+	delay := time.Duration(100+rand.Intn(400)) * time.Millisecond
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		return scrape.Result{URL: url, Error: ctx.Err()}
+	}
+
+	// Simulate occasional failures (20% chance)
+	if rand.Float32() < 0.2 {
+		return scrape.Result{
+			URL:   url,
+			Data:  "",
+			Error: fmt.Errorf("network error for %s", url),
+		}
+	}
+
+	return scrape.Result{
+		URL:   url,
+		Data:  fmt.Sprintf("Data from %s (fetched in %v)", url, delay),
+		Error: nil,
+	}
+}
+
+func printReport(report scrape.Report) {
+	for _, result := range report.Results {
+		fmt.Printf("URL: %s, Data: %s, Error: %v\n", result.URL, result.Data, result.Error)
+	}
+	fmt.Printf("Stopped by: %v\n", report.Err)
+	fmt.Printf("Finished: %d, unfinished: %d %v\n", len(report.Results), len(report.Unfinished), report.Unfinished)
+}
+
+func main() {
+	// Seed random number generator
+	rand.Seed(time.Now().UnixNano())
+
+	// URLs to fetch
+	urls := []string{
+		"https://example.com",
+		"https://google.com",
+		"https://github.com",
+		"https://stackoverflow.com",
+		"https://golang.org",
+		"https://medium.com",
+		"https://dev.to",
+		"https://reddit.com",
+		"https://news.ycombinator.com",
+		"https://dev.to",
+		"https://stackoverflow.com",
+		"https://golang.org",
+	}
+
+	// Ctrl-C cancels whichever run is in progress.
+	root, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	before := leakcheck.Take()
+	goroutines := runtime.NumGoroutine()
+
+	// 1. Deadline for the whole operation: 3 workers can't get through 12 URLs in 800ms.
+	fmt.Println("Starting scrape with an 800ms deadline...")
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(root, 800*time.Millisecond)
+	report := scrape.Run(ctx, urls, 3, fetchURLWithContext)
+	cancel()
+	printReport(report)
+	fmt.Printf("Total time: %v, goroutines before: %d, after: %d\n\n", time.Since(start), goroutines, runtime.NumGoroutine())
+
+	// 2. Manual cancel from another goroutine, as a shutdown handler would.
+	fmt.Println("Starting scrape with a manual cancel...")
+	start = time.Now()
+	ctx, cancel = context.WithCancel(root)
+	go func() {
+		time.Sleep(600 * time.Millisecond)
+		fmt.Println("Cancelling...")
+		cancel()
+	}()
+	report = scrape.Run(ctx, urls, 3, fetchURLWithContext)
+	cancel()
+	printReport(report)
+	time.Sleep(10 * time.Millisecond) // let the canceller goroutine above return
+	fmt.Printf("Total time: %v, goroutines before: %d, after: %d\n", time.Since(start), goroutines, runtime.NumGoroutine())
+
+	// Anything the scrapes started must be gone by now. Unlike comparing
+	// counts, leakcheck names the goroutines that are left, with their stacks.
+	if leaked := before.Leaks(leakcheck.WithTimeout(500 * time.Millisecond)); len(leaked) > 0 {
+		fmt.Print(leakcheck.Report(leaked))
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Problem 8: Advanced Select with Multiple Channels (Expert)
+// Goal: Handle multiple types of events using advanced select patterns.
+
+type FetchResult struct {
+	URL   string
+	Data  string
+	Error error
+}
+
+// fetchURLWithResult simulates fetching data, giving up when ctx is done
+func fetchURLWithResult(ctx context.Context, url string) FetchResult {
+	// Simulate network delay (300-1000ms, slow enough to pause mid-run)
+	delay := time.Duration(300+rand.Intn(700)) * time.Millisecond
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		return FetchResult{URL: url, Error: ctx.Err()}
+	}
+
+	// Simulate occasional failures (20% chance)
+	if rand.Float32() < 0.2 {
+		return FetchResult{
+			URL:   url,
+			Data:  "",
+			Error: fmt.Errorf("network error for %s", url),
+		}
+	}
+
+	return FetchResult{
+		URL:   url,
+		Data:  fmt.Sprintf("Data from %s (fetched in %v)", url, delay),
+		Error: nil,
+	}
+}
+
+// Control commands accepted while a scrape is running.
+type CommandKind int
+
+const (
+	Pause CommandKind = iota
+	Resume
+	AddURL
+	Stop
+)
+
+type Command struct {
+	Kind CommandKind
+	URL  string // for AddURL
+}
+
+type Summary struct {
+	Fetched    int
+	Failed     int
+	Unfinished []string
+	Reason     string
+}
+
+// orchestrate runs the scrape. A single select loop services every event
+// source: worker results and errors, dispatching the next URL, the progress
+// ticker, the global timeout, OS signals and the control channel.
+func orchestrate(urls []string, workers int, timeout time.Duration, control <-chan Command) Summary {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	jobs := make(chan string)
+	results := make(chan FetchResult)
+	errs := make(chan FetchResult)
+	wg := new(sync.WaitGroup)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for url := range jobs {
+				r := fetchURLWithResult(ctx, url)
+				out := results
+				if r.Error != nil {
+					out = errs
+				}
+				select {
+				case out <- r:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	progress := time.NewTicker(time.Second)
+	defer progress.Stop()
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	pending := append([]string(nil), urls...)
+	inFlight := map[string]int{}
+	paused := false
+	summary := Summary{}
+
+loop:
+	for len(pending) > 0 || len(inFlight) > 0 || paused {
+		// A nil channel blocks forever, so the dispatch case is only live
+		// while we have work and aren't paused.
+		var dispatch chan<- string
+		var next string
+		if !paused && len(pending) > 0 {
+			dispatch, next = jobs, pending[0]
+		}
+
+		select {
+		case dispatch <- next:
+			pending = pending[1:]
+			inFlight[next]++
+		case r := <-results:
+			done(inFlight, r.URL)
+			summary.Fetched++
+			fmt.Printf("URL: %s, Data: %s\n", r.URL, r.Data)
+		case r := <-errs:
+			done(inFlight, r.URL)
+			summary.Failed++
+			fmt.Printf("URL: %s, Error: %v\n", r.URL, r.Error)
+		case <-progress.C:
+			state := "running"
+			if paused {
+				state = "paused"
+			}
+			fmt.Printf("[progress] %s: fetched %d, failed %d, in flight %d, pending %d\n",
+				state, summary.Fetched, summary.Failed, len(inFlight), len(pending))
+		case <-deadline.C:
+			summary.Reason = "timeout"
+			break loop
+		case sig := <-signals:
+			summary.Reason = "signal " + sig.String()
+			break loop
+		case cmd, ok := <-control:
+			if !ok {
+				control = nil // no more commands; keep going with what we have
+				continue
+			}
+			switch cmd.Kind {
+			case Pause:
+				paused = true
+				fmt.Println("[control] paused; in-flight fetches will still finish")
+			case Resume:
+				paused = false
+				fmt.Println("[control] resumed")
+			case AddURL:
+				pending = append(pending, cmd.URL)
+				fmt.Printf("[control] added %s\n", cmd.URL)
+			case Stop:
+				summary.Reason = "stopped"
+				break loop
+			}
+		}
+	}
+	if summary.Reason == "" {
+		summary.Reason = "done"
+	}
+
+	// Graceful shutdown: no more dispatching, cancel in-flight fetches and
+	// wait for every worker to exit before reporting.
+	close(jobs)
+	cancel()
+	wg.Wait()
+
+	for url, n := range inFlight {
+		for i := 0; i < n; i++ {
+			summary.Unfinished = append(summary.Unfinished, url)
+		}
+	}
+	summary.Unfinished = append(summary.Unfinished, pending...)
+	return summary
+}
+
+func done(inFlight map[string]int, url string) {
+	inFlight[url]--
+	if inFlight[url] == 0 {
+		delete(inFlight, url)
+	}
+}
+
+// readCommands turns stdin lines into commands: "pause", "resume", "add <url>" and "stop".
+// The reader goroutine is left blocked on stdin when the scrape ends; main exits right after.
+func readCommands() <-chan Command {
+	control := make(chan Command)
+	go func() {
+		defer close(control)
+		sc := bufio.NewScanner(os.Stdin)
+		for sc.Scan() {
+			fields := strings.Fields(sc.Text())
+			if len(fields) == 0 {
+				continue
+			}
+			switch {
+			case fields[0] == "pause":
+				control <- Command{Kind: Pause}
+			case fields[0] == "resume":
+				control <- Command{Kind: Resume}
+			case fields[0] == "add" && len(fields) == 2:
+				control <- Command{Kind: AddURL, URL: fields[1]}
+			case fields[0] == "stop":
+				control <- Command{Kind: Stop}
+			default:
+				fmt.Println("commands: pause | resume | add <url> | stop")
+			}
+		}
+	}()
+	return control
+}
+
+func main() {
+	// Seed random number generator
+	rand.Seed(time.Now().UnixNano())
+
+	// URLs to fetch
+	urls := []string{
+		"https://example.com",
+		"https://google.com",
+		"https://github.com",
+		"https://stackoverflow.com",
+		"https://golang.org",
+		"https://medium.com",
+		"https://dev.to",
+		"https://reddit.com",
+		"https://news.ycombinator.com",
+		"https://go.dev",
+	}
+
+	fmt.Println("Starting orchestrated fetch (type pause, resume, add <url> or stop; Ctrl-C to abort)...")
+	start := time.Now()
+
+	summary := orchestrate(urls, 2, 30*time.Second, readCommands())
+
+	fmt.Printf("Finished (%s): fetched %d, failed %d, unfinished %v\n",
+		summary.Reason, summary.Fetched, summary.Failed, summary.Unfinished)
+	fmt.Printf("Total time: %v\n", time.Since(start))
+}
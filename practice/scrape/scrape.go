@@ -0,0 +1,111 @@
+package scrape
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Result is the outcome of fetching one URL.
+type Result struct {
+	URL   string
+	Data  string
+	Error error
+}
+
+// Fetch fetches one URL, giving up as soon as ctx is done.
+type Fetch func(ctx context.Context, url string) Result
+
+// Report is what Run managed before it finished or was cancelled.
+// A URL listed more than once is reported once per occurrence.
+type Report struct {
+	Results    []Result
+	Unfinished []string
+	Err        error // ctx.Err() if the scrape was cut short
+}
+
+type indexedResult struct {
+	index  int
+	result Result
+}
+
+// Run fetches urls with a pool of workers until everything is done or ctx
+// is cancelled. It only returns once every goroutine it started has exited.
+func Run(ctx context.Context, urls []string, workers int, fetch Fetch) Report {
+	jobs := make(chan int)
+	// Unbuffered on purpose: once the collector below stops reading, a worker
+	// holding a result is stuck on the send unless it also watches ctx.
+	results := make(chan indexedResult)
+	wg := new(sync.WaitGroup)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(jobs)
+		for i := range urls {
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	workersWg := new(sync.WaitGroup)
+	for w := 0; w < workers; w++ {
+		workersWg.Add(1)
+		go func() {
+			defer workersWg.Done()
+			for i := range jobs {
+				result := fetch(ctx, urls[i])
+				select {
+				case results <- indexedResult{index: i, result: result}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		workersWg.Wait()
+		close(results)
+	}()
+
+	finished := make([]bool, len(urls))
+	report := Report{}
+collect:
+	for {
+		select {
+		case r, ok := <-results:
+			if !ok {
+				break collect
+			}
+			if errors.Is(r.result.Error, context.Canceled) || errors.Is(r.result.Error, context.DeadlineExceeded) {
+				continue // interrupted mid-fetch: counts as unfinished
+			}
+			finished[r.index] = true
+			report.Results = append(report.Results, r.result)
+		case <-ctx.Done():
+			report.Err = ctx.Err()
+			break collect
+		}
+	}
+	// results can close before the select above sees ctx.Done, when every
+	// worker gave up on ctx at once; the scrape was still cut short.
+	if report.Err == nil {
+		report.Err = ctx.Err()
+	}
+
+	// Stop reading results; every worker sees ctx.Done instead of blocking forever.
+	wg.Wait()
+
+	for i, ok := range finished {
+		if !ok {
+			report.Unfinished = append(report.Unfinished, urls[i])
+		}
+	}
+	return report
+}
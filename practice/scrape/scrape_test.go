@@ -0,0 +1,93 @@
+package scrape
+
+import (
+	"conc/leakcheck"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+)
+
+func urls(n int) []string {
+	us := make([]string, n)
+	for i := range us {
+		us[i] = fmt.Sprintf("https://example.com/%d", i)
+	}
+	return us
+}
+
+func TestRunFetchesEverything(t *testing.T) {
+	defer leakcheck.Take().Check(t)
+
+	fetch := func(_ context.Context, url string) Result { return Result{URL: url, Data: "ok"} }
+	us := urls(20)
+	report := Run(context.Background(), us, 3, fetch)
+
+	if report.Err != nil || len(report.Unfinished) != 0 {
+		t.Fatalf("Err = %v, Unfinished = %q; want a complete run", report.Err, report.Unfinished)
+	}
+	var got []string
+	for _, r := range report.Results {
+		got = append(got, r.URL)
+	}
+	sort.Strings(got)
+	sort.Strings(us)
+	if fmt.Sprint(got) != fmt.Sprint(us) {
+		t.Errorf("fetched %q, want %q", got, us)
+	}
+}
+
+// TestRunCancelledMidRun cancels while every worker is inside a fetch, so
+// results closes at about the moment ctx.Done fires. Whichever the collector
+// sees first, the report must carry the error.
+func TestRunCancelledMidRun(t *testing.T) {
+	defer leakcheck.Take().Check(t)
+
+	for i := 0; i < 50; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		started := make(chan struct{}, 10)
+		fetch := func(ctx context.Context, url string) Result {
+			started <- struct{}{}
+			<-ctx.Done()
+			return Result{URL: url, Error: ctx.Err()}
+		}
+		go func() {
+			<-started
+			<-started
+			cancel()
+		}()
+		report := Run(ctx, urls(10), 2, fetch)
+		cancel()
+
+		if !errors.Is(report.Err, context.Canceled) {
+			t.Fatalf("run %d: Err = %v, want context.Canceled", i, report.Err)
+		}
+		if len(report.Results) != 0 || len(report.Unfinished) != 10 {
+			t.Fatalf("run %d: %d results, %d unfinished; want 0 and 10", i, len(report.Results), len(report.Unfinished))
+		}
+	}
+}
+
+func TestRunDeadlineKeepsFinishedResults(t *testing.T) {
+	defer leakcheck.Take().Check(t)
+
+	fetch := func(ctx context.Context, url string) Result {
+		if url == "https://example.com/0" {
+			return Result{URL: url, Data: "fast"}
+		}
+		<-ctx.Done()
+		return Result{URL: url, Error: ctx.Err()}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	report := Run(ctx, urls(5), 5, fetch)
+
+	if !errors.Is(report.Err, context.DeadlineExceeded) {
+		t.Errorf("Err = %v, want context.DeadlineExceeded", report.Err)
+	}
+	if len(report.Results) != 1 || report.Results[0].Data != "fast" || len(report.Unfinished) != 4 {
+		t.Errorf("got %+v, want the one fast result and 4 unfinished", report)
+	}
+}
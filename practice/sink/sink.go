@@ -0,0 +1,273 @@
+package sink
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Record is one fetch outcome as stored by a sink.
+type Record struct {
+	Time    time.Time     `json:"time"`
+	URL     string        `json:"url"`
+	Status  string        `json:"status"` // "ok" or "error"
+	Latency time.Duration `json:"latency_ns"`
+	Bytes   int           `json:"bytes"`
+	Error   string        `json:"error,omitempty"`
+}
+
+// NewRecord builds a Record from the fields every FetchResult in practice has.
+func NewRecord(url, data string, err error, latency time.Duration) Record {
+	r := Record{Time: time.Now(), URL: url, Status: "ok", Latency: latency, Bytes: len(data)}
+	if err != nil {
+		r.Status, r.Error = "error", err.Error()
+	}
+	return r
+}
+
+// Sink stores records. Implementations don't need to be safe for concurrent
+// use: Writer calls them from a single goroutine.
+type Sink interface {
+	Write(Record) error
+	Flush() error
+	Close() error
+}
+
+// JSONL writes one JSON object per line.
+type JSONL struct {
+	w   *bufio.Writer
+	c   io.Closer
+	enc *json.Encoder
+}
+
+// NewJSONL returns a JSON Lines sink on w. Close closes w if it is an io.Closer.
+func NewJSONL(w io.Writer) *JSONL {
+	bw := bufio.NewWriter(w)
+	c, _ := w.(io.Closer)
+	return &JSONL{w: bw, c: c, enc: json.NewEncoder(bw)}
+}
+
+func (s *JSONL) Write(r Record) error { return s.enc.Encode(r) }
+func (s *JSONL) Flush() error         { return s.w.Flush() }
+func (s *JSONL) Close() error         { return closeAfter(s.Flush(), s.c) }
+
+// CSV writes a header row followed by one row per record.
+type CSV struct {
+	w      *csv.Writer
+	c      io.Closer
+	header bool
+}
+
+// NewCSV returns a CSV sink on w. Close closes w if it is an io.Closer.
+func NewCSV(w io.Writer) *CSV {
+	c, _ := w.(io.Closer)
+	return &CSV{w: csv.NewWriter(w), c: c}
+}
+
+func (s *CSV) Write(r Record) error {
+	if !s.header {
+		s.header = true
+		if err := s.w.Write([]string{"time", "url", "status", "latency_ms", "bytes", "error"}); err != nil {
+			return err
+		}
+	}
+	return s.w.Write([]string{
+		r.Time.Format(time.RFC3339Nano),
+		r.URL,
+		r.Status,
+		strconv.FormatFloat(float64(r.Latency)/float64(time.Millisecond), 'f', 3, 64),
+		strconv.Itoa(r.Bytes),
+		r.Error,
+	})
+}
+
+func (s *CSV) Flush() error {
+	s.w.Flush()
+	return s.w.Error()
+}
+
+func (s *CSV) Close() error { return closeAfter(s.Flush(), s.c) }
+
+// SQL inserts records into a table, batching each Flush into one transaction.
+// The statements are plain SQLite syntax; the caller opens db with whichever
+// SQLite driver they link in, e.g. sql.Open("sqlite", "results.db") with
+// modernc.org/sqlite.
+type SQL struct {
+	db      *sql.DB
+	table   string
+	pending []Record
+}
+
+// NewSQLite creates table in db if needed and returns a sink writing to it.
+// Close does not close db.
+func NewSQLite(db *sql.DB, table string) (*SQL, error) {
+	_, err := db.Exec(fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	time TEXT NOT NULL,
+	url TEXT NOT NULL,
+	status TEXT NOT NULL,
+	latency_ns INTEGER NOT NULL,
+	bytes INTEGER NOT NULL,
+	error TEXT
+)`, quoteIdent(table)))
+	if err != nil {
+		return nil, err
+	}
+	return &SQL{db: db, table: table}, nil
+}
+
+func (s *SQL) Write(r Record) error {
+	s.pending = append(s.pending, r)
+	return nil
+}
+
+func (s *SQL) Flush() error {
+	if len(s.pending) == 0 {
+		return nil
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(fmt.Sprintf(`INSERT INTO %s (time, url, status, latency_ns, bytes, error) VALUES (?, ?, ?, ?, ?, ?)`, quoteIdent(s.table)))
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+	for _, r := range s.pending {
+		if _, err := stmt.Exec(r.Time.Format(time.RFC3339Nano), r.URL, r.Status, int64(r.Latency), r.Bytes, r.Error); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.pending = s.pending[:0]
+	return nil
+}
+
+func (s *SQL) Close() error { return s.Flush() }
+
+// quoteIdent quotes name as an SQLite identifier: wrapped in double quotes,
+// with any double quote inside doubled.
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func closeAfter(err error, c io.Closer) error {
+	if c == nil {
+		return err
+	}
+	return errors.Join(err, c.Close())
+}
+
+// Writer owns a Sink and feeds it from a dedicated goroutine. Send never
+// blocks on I/O: records are queued in memory and written in batches, with a
+// flush at least every interval and on Close.
+type Writer struct {
+	sink     Sink
+	interval time.Duration
+
+	mu      sync.Mutex
+	queue   []Record
+	closed  bool
+	wake    chan struct{} // buffered 1: "there is something to write"
+	done    chan struct{}
+	err     error
+	written int
+}
+
+// NewWriter starts the writer goroutine for sink.
+func NewWriter(sink Sink, interval time.Duration) *Writer {
+	w := &Writer{sink: sink, interval: interval, wake: make(chan struct{}, 1), done: make(chan struct{})}
+	go w.run()
+	return w
+}
+
+// Send queues r for writing. It returns an error only if the writer is closed.
+func (w *Writer) Send(r Record) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return errors.New("sink: send on closed writer")
+	}
+	w.queue = append(w.queue, r)
+	// Signal under the lock so Close can't close wake in between.
+	select {
+	case w.wake <- struct{}{}:
+	default: // already signalled
+	}
+	return nil
+}
+
+// Close writes everything still queued, flushes and closes the sink, and
+// returns the first error the writer hit.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.wake)
+	}
+	w.mu.Unlock()
+	<-w.done
+	return w.err
+}
+
+// Written reports how many records reached the sink. Call it after Close.
+func (w *Writer) Written() int {
+	return w.written
+}
+
+func (w *Writer) run() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	dirty := false
+	for {
+		select {
+		case _, ok := <-w.wake:
+			w.write()
+			dirty = true
+			if !ok {
+				w.keep(w.sink.Close())
+				return
+			}
+		case <-ticker.C:
+			if dirty {
+				w.keep(w.sink.Flush())
+				dirty = false
+			}
+		}
+	}
+}
+
+func (w *Writer) write() {
+	w.mu.Lock()
+	batch := w.queue
+	w.queue = nil
+	w.mu.Unlock()
+
+	for _, r := range batch {
+		if err := w.sink.Write(r); err != nil {
+			w.keep(err)
+			continue
+		}
+		w.written++
+	}
+}
+
+func (w *Writer) keep(err error) {
+	if err != nil && w.err == nil {
+		w.err = err
+	}
+}
@@ -0,0 +1,91 @@
+package sink
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+func TestSQLiteSink(t *testing.T) {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "results.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// A name that %q would have mangled into a string literal with a backslash.
+	const table = `fetch "results"`
+	s, err := NewSQLite(db, table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := NewWriter(s, time.Hour) // nothing flushes before Close
+	w.Send(NewRecord("https://a.example", "data", nil, 10*time.Millisecond))
+	w.Send(NewRecord("https://b.example", "", errors.New("boom"), 20*time.Millisecond))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if w.Written() != 2 {
+		t.Fatalf("Written = %d, want 2", w.Written())
+	}
+
+	rows, err := db.Query(`SELECT url, status, latency_ns, bytes, error FROM ` + quoteIdent(table) + ` ORDER BY url`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var got []Record
+	for rows.Next() {
+		var r Record
+		var latency int64
+		if err := rows.Scan(&r.URL, &r.Status, &latency, &r.Bytes, &r.Error); err != nil {
+			t.Fatal(err)
+		}
+		r.Latency = time.Duration(latency)
+		got = append(got, r)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	want := []Record{
+		{URL: "https://a.example", Status: "ok", Latency: 10 * time.Millisecond, Bytes: 4},
+		{URL: "https://b.example", Status: "error", Latency: 20 * time.Millisecond, Error: "boom"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d rows, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("row %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestJSONLSink(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(NewJSONL(&buf), time.Millisecond)
+	for i := 0; i < 3; i++ {
+		w.Send(NewRecord("https://example.com", "data", nil, time.Millisecond))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	dec := json.NewDecoder(&buf)
+	n := 0
+	for dec.More() {
+		var r Record
+		if err := dec.Decode(&r); err != nil {
+			t.Fatal(err)
+		}
+		n++
+	}
+	if n != 3 {
+		t.Fatalf("decoded %d records, want 3", n)
+	}
+}
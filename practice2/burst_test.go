@@ -0,0 +1,139 @@
+package main
+
+import (
+	"conc/leakcheck"
+	"conc/pool"
+	"context"
+	"math/rand/v2"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// A bursty synthetic workload: every burstGap a batch of burstSize tasks
+// arrives at once, each taking 20-60ms of work. With 3 fixed workers a burst
+// takes ~450ms to clear; letting the pool grow should cut the tail latency.
+const (
+	bursts    = 5
+	burstSize = 30
+	burstGap  = 500 * time.Millisecond
+)
+
+type burstTask struct {
+	id      int
+	arrived time.Time
+}
+
+func burstWorker(ctx context.Context, t burstTask) (time.Duration, error) {
+	time.Sleep(time.Duration(rand.IntN(41)+20) * time.Millisecond)
+	return time.Since(t.arrived), nil
+}
+
+type burstStats struct {
+	tasks         int
+	avg, p95, max time.Duration
+	peakWorkers   int
+	scaleEvents   int
+}
+
+func runBurst(t *testing.T, opts ...pool.Option) burstStats {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// OnScale fires from whichever goroutine grew or shrank the pool.
+	var mu sync.Mutex
+	st := burstStats{}
+	opts = append(opts, pool.OnScale(func(e pool.ScaleEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		st.scaleEvents++
+		if e.To > st.peakWorkers {
+			st.peakWorkers = e.To
+		}
+	}))
+	p := pool.New(ctx, burstWorker, opts...)
+	mu.Lock()
+	if p.Size() > st.peakWorkers {
+		st.peakWorkers = p.Size()
+	}
+	mu.Unlock()
+
+	go func() {
+		defer p.Close()
+		id := 0
+		for b := 0; b < bursts; b++ {
+			arrived := time.Now()
+			for i := 0; i < burstSize; i++ {
+				id++
+				if err := p.Submit(ctx, burstTask{id: id, arrived: arrived}); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+			time.Sleep(time.Until(arrived.Add(burstGap)))
+		}
+	}()
+
+	var latencies []time.Duration
+	for r := range p.Results() {
+		latencies = append(latencies, r.Out)
+	}
+	if err := p.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if len(latencies) != bursts*burstSize {
+		t.Fatalf("got %d results, want %d", len(latencies), bursts*burstSize)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	var total time.Duration
+	for _, l := range latencies {
+		total += l
+	}
+	st.tasks = len(latencies)
+	st.avg = total / time.Duration(len(latencies))
+	st.p95 = latencies[len(latencies)*95/100]
+	st.max = latencies[len(latencies)-1]
+	return st
+}
+
+// TestAutoscaleCutsBurstLatency runs the same bursty workload against a fixed
+// 3-worker pool and an autoscaling one (3-12 workers), and fails unless the
+// autoscaling pool's p95 latency is clearly lower.
+func TestAutoscaleCutsBurstLatency(t *testing.T) {
+	if testing.Short() {
+		t.Skip("takes about 5s")
+	}
+	defer leakcheck.Take().Check(t)
+
+	fixed := runBurst(t, pool.WithWorkers(3), pool.WithQueueSize(burstSize))
+	auto := runBurst(t,
+		pool.WithWorkers(3),
+		pool.WithMaxWorkers(12),
+		pool.WithQueueSize(burstSize),
+		pool.WithScaleUpDepth(6),
+		pool.WithScaleUpWait(50*time.Millisecond),
+		pool.WithIdleTimeout(200*time.Millisecond),
+	)
+	for _, r := range []struct {
+		name string
+		st   burstStats
+	}{{"fixed", fixed}, {"autoscale", auto}} {
+		t.Logf("%-10s tasks=%d avg=%v p95=%v max=%v peak workers=%d scale events=%d",
+			r.name, r.st.tasks, r.st.avg.Round(time.Millisecond), r.st.p95.Round(time.Millisecond),
+			r.st.max.Round(time.Millisecond), r.st.peakWorkers, r.st.scaleEvents)
+	}
+
+	if auto.peakWorkers <= 3 {
+		t.Errorf("autoscaling pool never grew past 3 workers")
+	}
+	// A fixed pool needs ~450ms per burst and an autoscaling one a fraction
+	// of that; require at least a 1.5x improvement so scheduler noise can't
+	// flip the result.
+	if auto.p95*3 >= fixed.p95*2 {
+		t.Errorf("autoscale p95 %v is not clearly below fixed p95 %v", auto.p95, fixed.p95)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"conc/dqueue"
+	"conc/pool"
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Durable mode: tasks live in an on-disk queue instead of taskCh, and a task
+// is only acked after it's been processed. If the process dies, whatever
+// wasn't acked (queued or in flight) is handed out again on the next run.
+
+// seedDurable puts tasks 1..10 into the queue at path.
+func seedDurable(path string) error {
+	q, err := dqueue.Open(path)
+	if err != nil {
+		return err
+	}
+	for i := 1; i <= 10; i++ {
+		if _, err := q.Put([]byte(strconv.Itoa(i))); err != nil {
+			q.Close()
+			return err
+		}
+	}
+	return q.Close()
+}
+
+// runDurable works through the queue at path with 3 workers until nothing is
+// left unacked, appending each processed task id to donePath. With
+// crashAfter > 0 the process exits abruptly, without acking, when it picks up
+// its crashAfter-th task.
+func runDurable(path, donePath string, crashAfter int) error {
+	q, err := dqueue.Open(path, dqueue.WithVisibilityTimeout(5*time.Second))
+	if err != nil {
+		return err
+	}
+	defer q.Close()
+
+	done, err := os.OpenFile(donePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	defer done.Close()
+	var doneMu sync.Mutex
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// Cancelling receiving stops the loop below; the pool keeps its own context
+	// so tasks already handed out still finish.
+	receiving, stopReceiving := context.WithCancel(ctx)
+	defer stopReceiving()
+
+	var started sync.Mutex
+	picked := 0
+	process := func(ctx context.Context, msg dqueue.Message) (struct{}, error) {
+		started.Lock()
+		picked++
+		if picked == crashAfter {
+			fmt.Printf("Worker %v crashing on task %s\n", pool.WorkerID(ctx), msg.Body)
+			os.Exit(1)
+		}
+		started.Unlock()
+
+		time.Sleep(time.Duration(rand.IntN(401)+100) * time.Millisecond)
+
+		doneMu.Lock()
+		_, err := fmt.Fprintf(done, "%s\n", msg.Body)
+		doneMu.Unlock()
+		if err != nil {
+			q.Nack(msg.ID)
+			return struct{}{}, err
+		}
+		if err := q.Ack(msg.ID); err != nil {
+			return struct{}{}, err
+		}
+		fmt.Printf("Worker %v processed task %s (attempt %d)\n", pool.WorkerID(ctx), msg.Body, msg.Attempts)
+		if q.Pending() == 0 {
+			stopReceiving()
+		}
+		return struct{}{}, nil
+	}
+
+	tasks := pool.New(ctx, process, pool.WithWorkers(3), pool.WithDiscardResults())
+	for q.Pending() > 0 {
+		msg, err := q.Receive(receiving)
+		if err != nil {
+			break
+		}
+		if err := tasks.Submit(ctx, msg); err != nil {
+			q.Nack(msg.ID)
+			break
+		}
+	}
+	return tasks.Wait()
+}
@@ -1,3 +1,7 @@
 module practice2
 
 go 1.25.0
+
+require conc v0.0.0
+
+replace conc => ../conc
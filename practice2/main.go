@@ -1,46 +1,134 @@
 package main
 
 import (
+	"conc/pool"
+	"conc/pqueue"
+	"conc/shutdown"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"math/rand/v2"
-	"sync"
+	"os"
 	"time"
 )
 
 // Problem 3
 
-func sender(taskCh chan<- int, wg *sync.WaitGroup) {
-	defer wg.Done()
+// sender queues tasks 1..10. Every third task is urgent and every task has to
+// start within 2s. Close works like close(taskCh): workers drain what's left.
+func sender(queue *pqueue.Queue[int]) {
+	defer queue.Close()
+	deadline := time.Now().Add(2 * time.Second)
 	for i := 1; i <= 10; i++ {
-		taskCh <- i
+		priority := 0
+		if i%3 == 0 {
+			priority = 1
+		}
+		queue.Push(i, priority, deadline)
 	}
-	close(taskCh)
 }
 
-func worker(id int, taskCh <-chan int, wg *sync.WaitGroup) {
-	defer wg.Done()
-	for ch := range taskCh {
-		sleep := rand.IntN(401) + 100
-		time.Sleep(time.Duration(sleep) * time.Millisecond)
-		fmt.Printf("Worker %v processed task %v\n", id, ch)
+// dispatch hands the best queued task to the pool each time a worker is free.
+// The pool has no queue of its own, so at most one task waits outside the
+// priority queue.
+func dispatch(ctx context.Context, queue *pqueue.Queue[int], tasks *pool.Pool[pqueue.Item[int], struct{}]) error {
+	defer tasks.Close()
+	for {
+		task, err := queue.Pop(ctx)
+		if errors.Is(err, pqueue.ErrClosed) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := tasks.Submit(ctx, task); err != nil {
+			return err
+		}
 	}
 }
 
+func worker(ctx context.Context, task pqueue.Item[int]) (struct{}, error) {
+	sleep := rand.IntN(401) + 100
+	select {
+	case <-time.After(time.Duration(sleep) * time.Millisecond):
+	case <-ctx.Done():
+		return struct{}{}, context.Cause(ctx)
+	}
+	fmt.Printf("Worker %v processed task %v (priority %v, done %v after queueing)\n",
+		pool.WorkerID(ctx), task.Value, task.Priority, time.Since(task.Enqueued).Round(time.Millisecond))
+	return struct{}{}, nil
+}
+
 func main() {
-	taskCh := make(chan int)
-	wg := &sync.WaitGroup{}
-	// wgWorker := &sync.WaitGroup{}
+	durable := flag.String("durable", "", "process tasks from the on-disk queue at this path (seeded with 1..10 if missing)")
+	donePath := flag.String("done", "done.log", "durable mode: file processed task ids are appended to")
+	crashAfter := flag.Int("crash-after", 0, "durable mode: exit abruptly when picking up the n-th task")
+	flag.Parse()
+	switch {
+	case *durable != "":
+		if _, err := os.Stat(*durable); errors.Is(err, os.ErrNotExist) {
+			if err := seedDurable(*durable); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		}
+		if err := runDurable(*durable, *donePath, *crashAfter); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Println("All workers finished!")
+		return
+	}
+
+	// Ctrl-C cancels ctx: dispatch stops handing out tasks and the pool tears
+	// down, cancelling the tasks in flight; shutdown then gives the workers up
+	// to 5s to return before a forced exit.
+	sd := shutdown.New(shutdown.WithGrace(5 * time.Second))
+	ctx := sd.Context()
 
-	wg.Add(1)
-	go sender(taskCh, wg)
+	// Tasks wait in a priority queue: urgent tasks go first, and each second a
+	// task waits counts as one extra priority level so nothing starves.
+	queue := pqueue.New(
+		pqueue.WithAging[int](time.Second),
+		pqueue.OnExpire(func(task pqueue.Item[int]) {
+			fmt.Printf("Task %v missed its deadline\n", task.Value)
+		}),
+	)
 
-	// wgWorker.Add(3)
-	wg.Add(3)
-	go worker(1, taskCh, wg)
-	go worker(2, taskCh, wg)
-	go worker(3, taskCh, wg)
+	// The pool owns the worker goroutines, and Wait returns once they have all
+	// exited. It starts with 3 workers and adds up to 3 more while tasks are
+	// backing up.
+	tasks := pool.New(ctx, worker,
+		pool.WithWorkers(3),
+		pool.WithMaxWorkers(6),
+		pool.WithScaleUpWait(200*time.Millisecond),
+		pool.WithIdleTimeout(300*time.Millisecond),
+		pool.WithDiscardResults(), // workers print their own progress
+		pool.OnScale(func(e pool.ScaleEvent) {
+			fmt.Printf("Pool %d -> %d workers (%s)\n", e.From, e.To, e.Reason)
+		}),
+	)
 
-	wg.Wait()
-	// wgWorker.Wait()
+	// The pool has stopped once its workers have returned; task errors are
+	// main's to report, not the shutdown's.
+	sd.Register("pool", func(ctx context.Context) error {
+		tasks.Wait()
+		return nil
+	})
+
+	sender(queue)
+	if err := dispatch(ctx, queue, tasks); err != nil {
+		fmt.Println("dispatch stopped:", err)
+	}
+
+	// Let the tasks already handed out finish; after Ctrl-C they have been
+	// cancelled, and their errors just say so.
+	if err := tasks.Wait(); err != nil && ctx.Err() == nil {
+		fmt.Println("some tasks failed:", err)
+	}
+	if rep := sd.Shutdown(nil); errors.Is(rep.Cause, shutdown.ErrSignal) {
+		fmt.Print(rep)
+	}
 	fmt.Println("All workers finished!")
 }
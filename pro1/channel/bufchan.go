@@ -0,0 +1,39 @@
+package channel
+
+import (
+	"conc/bufchan"
+	"fmt"
+)
+
+// UnboundedChan is BufferChan without the cap(c) limit: the third send that
+// would block make(chan int, 2) goes straight through, because the buffer
+// grows instead.
+func UnboundedChan() {
+	u := bufchan.NewUnbounded[int]()
+	for _, v := range []int{3, 5, 7} {
+		u.In() <- v // never blocks, even with nobody receiving yet
+	}
+	close(u.In())
+	for x := range u.Out() {
+		fmt.Println(x) // 3, 5, 7
+	}
+	st := u.Stats()
+	fmt.Printf("sent %d, peak %d, backing array %d slots / %d bytes\n", st.Sent, st.Peak, st.Cap, st.Bytes)
+}
+
+// RingChan sends five values into two-slot rings with nobody receiving:
+// each ring keeps two and counts the three it had to drop.
+func RingChan() {
+	for _, policy := range []bufchan.Overflow{bufchan.DropOldest, bufchan.DropNewest} {
+		r := bufchan.NewRing[int](2, policy)
+		for v := 1; v <= 5; v++ {
+			r.In() <- v
+		}
+		close(r.In())
+		var kept []int
+		for x := range r.Out() {
+			kept = append(kept, x)
+		}
+		fmt.Println(policy, kept, "dropped", r.Dropped()) // drop-oldest [4 5] / drop-newest [1 2], dropped 3
+	}
+}
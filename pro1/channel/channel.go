@@ -1,6 +1,8 @@
 package channel
 
 import (
+	"conc/chanx"
+	"context"
 	"fmt"
 	"strconv"
 )
@@ -46,15 +48,23 @@ func MyGoroutine() {
 	close(intChann)
 }
 
-func intChannFunc(num int, intChann chan<- int) {
-	intChann <- num * 2
+func double(num int) int {
+	return num * 2
 }
 
-func strChannFunc(str string, strChann chan<- string) {
+func countUp(str string) string {
 	for i := 1; i <= 10; i++ {
 		str += strconv.Itoa(i)
 	}
-	strChann <- str
+	return str
+}
+
+func intChannFunc(num int, intChann chan<- int) {
+	intChann <- double(num)
+}
+
+func strChannFunc(str string, strChann chan<- string) {
+	strChann <- countUp(str)
 }
 
 func DiffTypeChann() {
@@ -63,15 +73,13 @@ func DiffTypeChann() {
 	go intChannFunc(3, intChann)
 	go strChannFunc("0", strChann)
 
-	intVal := 0
-	strVal := ""
-	for i := 0; i < 2; i++ {
-		select {
-		case intVal = <-intChann:
-			fmt.Println(intVal)
-		case strVal = <-strChann:
-			fmt.Println(strVal)
-		}
+	// Map turns the ints into strings so both streams fit one merged channel,
+	// and Take stops after the one value each sender produces.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	merged := chanx.Merge(ctx, chanx.Map(ctx, intChann, strconv.Itoa), strChann)
+	for v := range chanx.Take(ctx, merged, 2) {
+		fmt.Println(v)
 	}
 	close(intChann)
 	close(strChann)
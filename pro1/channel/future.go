@@ -0,0 +1,50 @@
+package channel
+
+import (
+	"conc/future"
+	"context"
+	"fmt"
+)
+
+// DiffTypeFuture computes the same two values as DiffTypeChann, but each
+// goroutine's result comes back as a typed future instead of through a
+// single-use channel, and both are awaited together.
+func DiffTypeFuture() {
+	ctx := context.Background()
+
+	intF := future.Go(ctx, func(ctx context.Context) (int, error) {
+		return double(3), nil
+	})
+	strF := future.Go(ctx, func(ctx context.Context) (string, error) {
+		return countUp("0"), nil
+	})
+	// Chain more work onto a result without waiting for it here.
+	lenF := future.Then(ctx, strF, func(ctx context.Context, s string) (int, error) {
+		return len(s), nil
+	})
+
+	// Different types, one wait.
+	if err := future.Wait(ctx, intF, strF, lenF); err != nil {
+		fmt.Println(err)
+		return
+	}
+	intVal, _ := intF.Await(ctx)
+	strVal, _ := strF.Await(ctx)
+	strLen, _ := lenF.Await(ctx)
+	fmt.Println(intVal)
+	fmt.Println(strVal, strLen)
+
+	// A panic inside a future comes back as an error instead of killing the process.
+	boom := future.Go(ctx, func(ctx context.Context) (int, error) {
+		var m map[string]int
+		m["boom"]++
+		return 0, nil
+	})
+	// Any skips failed futures and settles with the first success.
+	if v, err := future.Any(ctx, boom, future.Resolved(42)).Await(ctx); err == nil {
+		fmt.Println("first success:", v)
+	}
+	if _, err := boom.Await(ctx); err != nil {
+		fmt.Println("recovered:", err.(*future.PanicError).Value)
+	}
+}
@@ -0,0 +1,46 @@
+package channel
+
+import (
+	"conc/chanx"
+	"conc/leakcheck"
+	"context"
+	"fmt"
+	"time"
+)
+
+// LeakCheck runs MyGoroutine and a variant that stops reading early under
+// leakcheck. MyGoroutine receives all ten values, so all ten senders finish;
+// shortRead takes only four, the buffer holds three more and the last three
+// senders are stuck on intChann forever.
+func LeakCheck() {
+	before := leakcheck.Take()
+	MyGoroutine()
+	reportLeaks("MyGoroutine", before)
+
+	before = leakcheck.Take()
+	shortRead(4)
+	reportLeaks("shortRead", before)
+}
+
+func shortRead(reads int) {
+	intChann := make(chan int, 3)
+	for i := 0; i <= 9; i++ {
+		go func(i int) {
+			intChann <- i
+		}(i)
+	}
+	for range chanx.Take(context.Background(), intChann, reads) {
+	}
+}
+
+func reportLeaks(name string, before *leakcheck.Snapshot) {
+	leaked := before.Leaks(leakcheck.WithTimeout(200 * time.Millisecond))
+	if len(leaked) == 0 {
+		fmt.Println(name, "leaked no goroutines")
+		return
+	}
+	fmt.Println(name, "leaked", len(leaked), "goroutines:")
+	for _, g := range leaked {
+		fmt.Printf("  goroutine %d [%s] in %s\n", g.ID, g.State, g.Top())
+	}
+}
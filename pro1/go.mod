@@ -1,3 +1,7 @@
 module proj1
 
-go 1.20
+go 1.21
+
+require conc v0.0.0
+
+replace conc => ../conc
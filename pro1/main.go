@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	channDir "proj1/channel"
 	waitGroup "proj1/waitgroup"
@@ -8,13 +9,28 @@ import (
 
 // https://www.codecademy.com/resources/docs/go/goroutines
 func main() {
+	leaks := flag.Bool("leaks", false, "also run a reader that stops early and report the goroutines it leaks")
+	flag.Parse()
+
 	channDir.MyGoroutine()
 	fmt.Println()
+	if *leaks {
+		channDir.LeakCheck()
+		fmt.Println()
+	}
 	channDir.DiffTypeChann()
+	channDir.DiffTypeFuture()
 	channDir.BufferChan()
+	channDir.UnboundedChan()
+	channDir.RingChan()
 	fmt.Println("------------------")
 	waitGroup.Execute()
 	fmt.Println()
-	waitGroup.DiffTypeWg()
+	num, str, err := waitGroup.DiffTypeWg()
+	if err != nil {
+		fmt.Println(err)
+	}
+	fmt.Println(num)
+	fmt.Println(str)
 	fmt.Println("------------")
 }
@@ -0,0 +1,123 @@
+package waitgroup
+
+import (
+	"conc/safego"
+	"context"
+	"errors"
+	"sync"
+)
+
+// Group is a sync.WaitGroup whose goroutines can fail.
+//
+// Go launches a task; Wait waits for all of them and returns the first error
+// (or every error joined, after JoinErrors). The context handed to tasks is
+// cancelled as soon as one of them fails, so the others can stop early.
+// SetLimit caps how many tasks run at once.
+//
+// A zero Group is ready to use, like a sync.WaitGroup: its tasks get
+// context.Background and a failure cancels nothing.
+type Group struct {
+	cancel  context.CancelCauseFunc
+	ctx     context.Context
+	wg      sync.WaitGroup
+	sem     chan struct{}
+	joinAll bool
+
+	mu   sync.Mutex
+	errs []error
+}
+
+// WithContext returns a Group and the context its tasks receive, derived from ctx.
+func WithContext(ctx context.Context) (*Group, context.Context) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	return &Group{ctx: ctx, cancel: cancel}, ctx
+}
+
+// SetLimit allows at most n tasks to run at once; Go blocks until a slot frees
+// up. n <= 0 removes the limit. It must be called before the first Go.
+func (g *Group) SetLimit(n int) {
+	if n <= 0 {
+		g.sem = nil
+		return
+	}
+	g.sem = make(chan struct{}, n)
+}
+
+// JoinErrors makes Wait return every task error joined with errors.Join
+// instead of only the first one.
+func (g *Group) JoinErrors() {
+	g.joinAll = true
+}
+
+// Go runs fn in a new goroutine. A panic in fn is recovered and counts as the
+// task's error (a *safego.PanicError).
+func (g *Group) Go(fn func(ctx context.Context) error) {
+	if g.sem != nil {
+		g.sem <- struct{}{}
+	}
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		if g.sem != nil {
+			defer func() { <-g.sem }()
+		}
+		ctx := g.ctx
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		err := safego.Call("waitgroup task", func() error { return fn(ctx) })
+		if err != nil {
+			g.mu.Lock()
+			g.errs = append(g.errs, err)
+			g.mu.Unlock()
+			if g.cancel != nil {
+				g.cancel(err)
+			}
+		}
+	}()
+}
+
+// Task is the value of a task started with GoValue.
+type Task[T any] struct {
+	v    T
+	done chan struct{}
+}
+
+// GoValue runs fn in g like Go and keeps the value it returns, so tasks hand
+// back results instead of writing to variables they share with the caller.
+func GoValue[T any](g *Group, fn func(ctx context.Context) (T, error)) *Task[T] {
+	t := &Task[T]{done: make(chan struct{})}
+	g.Go(func(ctx context.Context) error {
+		defer close(t.done)
+		v, err := fn(ctx)
+		t.v = v
+		return err
+	})
+	return t
+}
+
+// Value waits for the task and returns what it produced, which may be
+// partial or zero if it failed; check the group's Wait for the error.
+func (t *Task[T]) Value() T {
+	<-t.done
+	return t.v
+}
+
+// Wait blocks until every task has returned, then cancels the group's context
+// and returns the error(s).
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	if g.cancel != nil {
+		g.cancel(nil)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if len(g.errs) == 0 {
+		return nil
+	}
+	if g.joinAll {
+		return errors.Join(g.errs...)
+	}
+	return g.errs[0]
+}
@@ -0,0 +1,95 @@
+package waitgroup
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestZeroGroupReportsFailure(t *testing.T) {
+	var g Group
+	boom := errors.New("boom")
+	g.Go(func(context.Context) error { return boom })
+	g.Go(func(context.Context) error { return nil })
+	if err := g.Wait(); !errors.Is(err, boom) {
+		t.Fatalf("Wait = %v, want %v", err, boom)
+	}
+}
+
+func TestGoValueAfterFailure(t *testing.T) {
+	g, _ := WithContext(context.Background())
+	boom := errors.New("boom")
+	ok := GoValue(g, func(context.Context) (int, error) { return 1, nil })
+	bad := GoValue(g, func(context.Context) (string, error) { panic(boom) })
+	if err := g.Wait(); !errors.Is(err, boom) {
+		t.Fatalf("Wait = %v, want the panic", err)
+	}
+	if ok.Value() != 1 || bad.Value() != "" {
+		t.Fatalf("values = %d, %q", ok.Value(), bad.Value())
+	}
+}
+
+func TestFirstErrorCancelsSiblings(t *testing.T) {
+	g, ctx := WithContext(context.Background())
+	boom := errors.New("boom")
+	sibling := make(chan error, 1)
+	g.Go(func(ctx context.Context) error {
+		<-ctx.Done()
+		sibling <- context.Cause(ctx)
+		return nil
+	})
+	g.Go(func(context.Context) error { return boom })
+	if err := g.Wait(); !errors.Is(err, boom) {
+		t.Fatalf("Wait = %v, want %v", err, boom)
+	}
+	if cause := <-sibling; !errors.Is(cause, boom) {
+		t.Errorf("sibling saw cause %v, want %v", cause, boom)
+	}
+	if !errors.Is(context.Cause(ctx), boom) {
+		t.Errorf("group context cause = %v, want %v", context.Cause(ctx), boom)
+	}
+}
+
+func TestSetLimit(t *testing.T) {
+	var g Group
+	g.SetLimit(2)
+	var running, peak atomic.Int32
+	for i := 0; i < 10; i++ {
+		g.Go(func(context.Context) error {
+			n := running.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			running.Add(-1)
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if p := peak.Load(); p != 2 {
+		t.Errorf("peak concurrency = %d, want 2", p)
+	}
+}
+
+func TestJoinErrors(t *testing.T) {
+	var g Group
+	g.JoinErrors()
+	errA, errB := errors.New("a"), errors.New("b")
+	g.Go(func(context.Context) error { return errA })
+	g.Go(func(context.Context) error { return errB })
+	g.Go(func(context.Context) error { return nil })
+	err := g.Wait()
+	if !errors.Is(err, errA) || !errors.Is(err, errB) {
+		t.Fatalf("Wait = %v, want both errors", err)
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); !ok || len(joined.Unwrap()) != 2 {
+		t.Errorf("Wait = %#v, want an errors.Join of the two errors", err)
+	}
+}
@@ -1,6 +1,7 @@
 package waitgroup
 
 import (
+	"context"
 	"fmt"
 	"sync"
 )
@@ -34,21 +35,19 @@ func appendStr(str string) string {
 	return str
 }
 
-func DiffTypeWg() {
-	wg := new(sync.WaitGroup)
-	num := 0
-	str := "c"
-	wg.Add(1)
-	go func(n int) {
-		num = addOne(n)
-		wg.Done()
-	}(num)
-	wg.Add(1)
-	go func(s string) {
-		str = appendStr(s)
-		wg.Done()
-	}(str)
-	wg.Wait()
-	fmt.Println(num)
-	fmt.Println(str)
+// DiffTypeWg runs two tasks of different result types in a Group. Each task
+// returns its value and an error, so nothing is shared between them and a
+// failure in either comes back from Wait.
+func DiffTypeWg() (int, string, error) {
+	g, _ := WithContext(context.Background())
+	num := GoValue(g, func(ctx context.Context) (int, error) {
+		return addOne(0), ctx.Err()
+	})
+	str := GoValue(g, func(ctx context.Context) (string, error) {
+		return appendStr("c"), ctx.Err()
+	})
+	if err := g.Wait(); err != nil {
+		return 0, "", err
+	}
+	return num.Value(), str.Value(), nil
 }
@@ -1,3 +1,7 @@
 module pro3
 
 go 1.21.9
+
+require conc v0.0.0
+
+replace conc => ../conc
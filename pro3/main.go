@@ -1,9 +1,19 @@
 package main
 
-import "fmt"
+import (
+	"flag"
+	"fmt"
+)
 
 // https://stackoverflow.com/questions/18660533/why-does-the-use-of-an-unbuffered-channel-in-the-same-goroutine-result-in-a-dead
 func main() {
+	mode := flag.String("watchdog", "", "rerun the deadlock with other goroutines alive, under the watchdog: chan, lock or mixed")
+	flag.Parse()
+	if *mode != "" {
+		watched(*mode)
+		return
+	}
+
 	c := make(chan int)
 	c <- 1
 	fmt.Println(<-c)
@@ -31,4 +41,9 @@ func main() {
    ch <- 20 // Blocked: Because Buffer size is already full and no one is waiting to recieve the Data  from channel
    <- ch
    <- ch
+5. When the sender must never block, no cap is big enough - use conc/bufchan instead (see pro1 UnboundedChan / RingChan):
+	- bufchan.NewUnbounded grows its buffer, so c <- 1 with no receiver yet just queues the value (memory use shows up in Stats)
+	- bufchan.NewRing keeps a fixed number of values and drops the oldest or newest on overflow, counting the drops
+6. The runtime only reports a deadlock when every goroutine is asleep. If anything else is still running (a ticker, an http server), a
+   stuck goroutine just hangs silently - run with -watchdog chan (or -watchdog lock) to see conc/watchdog catch it anyway.
 */
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"conc/watchdog"
+	"context"
+	"fmt"
+	"os"
+	"time"
+)
+
+// watched runs the deadlock from main where the runtime can't see it: a
+// ticker goroutine keeps running, so not "all goroutines are asleep" and
+// the program would just hang. The watchdog notices instead and exits with a
+// report. mode "chan" is main's c <- 1 with nobody receiving; mode "lock" is
+// two goroutines taking two mutexes in opposite order; mode "mixed" is a
+// producer that sends while holding a lock the consumer takes between receives.
+func watched(mode string) {
+	w := watchdog.New(
+		watchdog.WithThreshold(time.Second),
+		watchdog.OnDeadlock(func(r watchdog.Report) {
+			fmt.Print(r)
+			os.Exit(2)
+		}),
+	)
+	w.Start(context.Background())
+
+	go func() {
+		for i := 1; ; i++ {
+			time.Sleep(300 * time.Millisecond)
+			fmt.Println("still ticking", i)
+		}
+	}()
+
+	switch mode {
+	case "chan":
+		c := watchdog.NewChan[int](w, "c", 0)
+		c.Send(1) // blocks forever: the only receiver is the line below
+		fmt.Println(c.Recv())
+	case "lock":
+		a, b := w.NewMutex("a"), w.NewMutex("b")
+		lockBoth := func(first, second *watchdog.Mutex) {
+			first.Lock()
+			time.Sleep(100 * time.Millisecond)
+			second.Lock()
+			second.Unlock()
+			first.Unlock()
+		}
+		go lockBoth(a, b)
+		lockBoth(b, a)
+	case "mixed":
+		mu := w.NewMutex("mu")
+		c := watchdog.NewChan[int](w, "c", 0)
+		go func() {
+			for i := 0; ; i++ {
+				mu.Lock()
+				c.Send(i) // blocks once the consumer waits for mu
+				mu.Unlock()
+			}
+		}()
+		for {
+			v, _ := c.Recv()
+			mu.Lock()
+			fmt.Println("consumed", v)
+			mu.Unlock()
+		}
+	default:
+		fmt.Println("unknown -watchdog mode", mode)
+		return
+	}
+	fmt.Println("not reached")
+}
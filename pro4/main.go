@@ -1,6 +1,9 @@
 package main
 
 import (
+	"conc/chanx"
+	"conc/mux"
+	"context"
 	"fmt"
 	"time"
 )
@@ -11,6 +14,12 @@ func fast(num int, out chan<- int) {
 	out <- result
 }
 
+func medium(num int, out chan<- int) {
+	result := num * 2
+	time.Sleep(10 * time.Millisecond)
+	out <- result
+}
+
 func slow(num int, out chan<- int) {
 	result := num * 2
 	time.Sleep(15 * time.Millisecond)
@@ -18,25 +27,48 @@ func slow(num int, out chan<- int) {
 }
 
 func main() {
-	channels := make([]chan int, 2)
-	channels[0] = make(chan int)
-	channels[1] = make(chan int)
 	/*
-		OR:
-			var channels []chan int
-			channels = append(channels, make(chan int))
-			channels = append(channels, make(chan int))
+		The old version made channels := make([]chan int, 2) and then hardcoded
+		case <-channels[0] and case <-channels[1], so adding a third runner meant
+		editing the select. A mux selects over however many channels it holds and
+		tells us which one fired, so the runners can just live in a slice.
 	*/
+	runners := []struct {
+		name string
+		run  func(int, chan<- int)
+	}{
+		{"fast", fast},
+		{"medium", medium},
+		{"slow", slow},
+	}
+
+	// Runners still going at the cutoff are out of the race: OrDone closes
+	// their channel when ctx expires, and the mux reports the close.
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
 
-	go fast(2, channels[0])
-	go slow(3, channels[1])
+	m := mux.NewReflect[int]()
+	defer m.Close()
+	names := map[int]string{}
+	for i, r := range runners {
+		ch := make(chan int, 1)
+		names[m.Add(chanx.OrDone(ctx, ch))] = r.name
+		go r.run(i+2, ch)
+	}
 
-	for i := 0; i < 2; i++ {
-		select {
-		case res := <-channels[0]:
-			fmt.Println("fast finished first, result: ", res)
-		case res := <-channels[1]:
-			fmt.Println("slow finished first, result: ", res)
+	for place := 1; m.Len() > 0; {
+		ev, err := m.Recv(context.Background())
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		if ev.Closed {
+			fmt.Printf("%s did not finish\n", names[ev.Index])
+			continue
 		}
+		fmt.Printf("%s finished #%d, result: %d\n", names[ev.Index], place, ev.Value)
+		place++
+		// Each runner sends once, so we're done with its channel.
+		m.Remove(ev.Index)
 	}
 }
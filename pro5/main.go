@@ -1,11 +1,14 @@
 package main
 
 import (
+	"conc/shutdown"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"sync"
 	"time"
 )
 
@@ -105,15 +108,29 @@ func fetchWeather(city string) (Data, error) {
 	return data, nil
 }
 
-func fetchWeather2(city string, ch chan<-Data, chErr chan<-error) {
+// fetchWeather2 reports through ch or chErr, and gives up on both the request
+// and the send once ctx is cancelled, so a shutdown never leaves it stuck.
+func fetchWeather2(ctx context.Context, city string, ch chan<-Data, chErr chan<-error) {
 
 	data := Data{}
+	fail := func(err error) {
+		select {
+		case chErr<- err:
+		case <-ctx.Done():
+		}
+	}
 
 	// https://openweathermap.org/current , https://home.openweathermap.org/api_keys
 	url := fmt.Sprintf("https://api.openweathermap.org/data/2.5/weather?q=%s&appid=%s", city, apiKey)
-	resp, err := http.Get(url)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		fail(err)
+		return
+	}
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		chErr<- err
+		fail(err)
+		return
 	}
 
 	defer resp.Body.Close()
@@ -121,11 +138,13 @@ func fetchWeather2(city string, ch chan<-Data, chErr chan<-error) {
 	// my way
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		chErr<- err
+		fail(err)
+		return
 	}
 
 	if err := json.Unmarshal(body, &data); err != nil {
-		chErr<- err
+		fail(err)
+		return
 	}
 
 	// another way
@@ -134,11 +153,19 @@ func fetchWeather2(city string, ch chan<-Data, chErr chan<-error) {
 	// 	return data
 	// }
 
-	ch <- data
+	select {
+	case ch <- data:
+	case <-ctx.Done():
+	}
 }
 
 func main() {
 	startNow := time.Now()
+	// Ctrl-C cancels ctx: requests in flight are aborted, the loop below stops
+	// waiting and the fetchers get up to 5s to return before a forced exit.
+	sd := shutdown.New(shutdown.WithGrace(5 * time.Second))
+	ctx := sd.Context()
+	wg := new(sync.WaitGroup)
 
 	ch := make(chan Data, 5)
 	chErr := make(chan error)
@@ -153,20 +180,54 @@ func main() {
 	data := Data{}
 	err := errors.New("")
 	for _, city := range cities {
-		go fetchWeather2(city, ch, chErr)
+		wg.Add(1)
+		go func(city string) {
+			defer wg.Done()
+			fetchWeather2(ctx, city, ch, chErr)
+		}(city)
 	}
+	// The fetchers are the only senders, so the channels close once every one
+	// of them has returned; a fetcher stuck past the grace period leaves them
+	// open instead of sending on a closed channel.
+	fetched := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(ch)
+		close(chErr)
+		close(fetched)
+	}()
+	sd.Register("fetchers", func(ctx context.Context) error {
+		select {
+		case <-fetched:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
 
+loop:
 	for i := 0; i < len(cities); i++ {
 		select {
-		case err = <-chErr:
+		case <-ctx.Done():
+			break loop
+		case e, ok := <-chErr:
+			if !ok {
+				break loop // every fetcher has given up
+			}
+			err = e
 			fmt.Printf("some err: %s", err.Error())
-		case data = <-ch:
+		case d, ok := <-ch:
+			if !ok {
+				break loop
+			}
+			data = d
 			fmt.Printf("This is the temp %v from %s\n", data.MainField.Temp, cities[i])
 		}
 	}
 
-	close(ch)
-	close(chErr)
+	if rep := sd.Shutdown(nil); errors.Is(rep.Cause, shutdown.ErrSignal) {
+		fmt.Print(rep)
+	}
 
 	fmt.Println("This operation took: ", time.Since(startNow))
 }
\ No newline at end of file
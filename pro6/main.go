@@ -1,32 +1,117 @@
 package main
 
 import (
+	"conc/shutdown"
+	"conc/supervisor"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"sync"
+	"strings"
 	"time"
 )
 
-func count(thing string) {
+// count prints thing forever, or until ctx is done. With failAt > 0 it gives
+// up on that count instead: an error, or a panic if panics is set.
+func count(ctx context.Context, thing string, failAt int, panics bool) error {
 	for i := 1; true; i++ {
+		if i == failAt {
+			if panics {
+				panic(fmt.Sprint(thing, " fell over"))
+			}
+			return errors.New(thing + " got tired")
+		}
 		fmt.Println(i, thing)
-		time.Sleep(time.Millisecond * 500)
+		select {
+		case <-time.After(time.Millisecond * 500):
+		case <-ctx.Done():
+			return nil
+		}
 	}
+	return nil
 }
 
+// sheep and fish run under a supervisor: fish keeps failing and is restarted
+// according to the strategy, until Ctrl-C / SIGTERM (or the -for timer) shuts
+// everything down, or until fish fails more often than -max-restarts allows.
+// -stubborn makes sheep ignore the shutdown, so the grace period runs out and
+// the process is forced to exit with a report.
 func main() {
-	wg := &sync.WaitGroup{}
-
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		count("sheep")
-	}()
-
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		count("fish")
-	}()
-
-	wg.Wait()
-}
\ No newline at end of file
+	strategy := flag.String("strategy", "one-for-one", "restart strategy: one-for-one, one-for-all or rest-for-one")
+	runFor := flag.Duration("for", 0, "shut down after this long (0 = run until interrupted)")
+	failAt := flag.Int("fail-at", 4, "fish fails on this count (0 = never)")
+	panics := flag.Bool("panic", false, "fish panics instead of returning an error")
+	maxRestarts := flag.Int("max-restarts", 3, "restarts allowed within -window before giving up")
+	window := flag.Duration("window", 5*time.Second, "restart intensity window")
+	grace := flag.Duration("grace", 3*time.Second, "how long shutdown waits for the supervisor to stop")
+	stubborn := flag.Bool("stubborn", false, "sheep ignores shutdown requests")
+	flag.Parse()
+
+	strategies := map[string]supervisor.Strategy{
+		"one-for-one":  supervisor.OneForOne,
+		"one-for-all":  supervisor.OneForAll,
+		"rest-for-one": supervisor.RestForOne,
+	}
+	st, ok := strategies[*strategy]
+	if !ok {
+		fmt.Println("unknown strategy", *strategy)
+		return
+	}
+
+	sd := shutdown.New(shutdown.WithGrace(*grace))
+
+	sup := supervisor.New(
+		supervisor.WithStrategy(st),
+		supervisor.WithIntensity(*maxRestarts, *window),
+		supervisor.OnEvent(func(e supervisor.Event) {
+			switch {
+			case e.Kind == supervisor.Exited && e.Err != nil:
+				fmt.Printf("[%s] %s: %s\n", e.Child, e.Kind, firstLine(e.Err))
+			case e.Kind == supervisor.Restarted:
+				fmt.Printf("[%s] %s (#%d)\n", e.Child, e.Kind, e.Restarts)
+			default:
+				fmt.Printf("[%s] %s\n", e.Child, e.Kind)
+			}
+		}),
+	)
+	sup.Add("sheep", func(ctx context.Context) error {
+		if *stubborn {
+			ctx = context.Background()
+		}
+		return count(ctx, "sheep", 0, false)
+	})
+	sup.Add("fish", func(ctx context.Context) error { return count(ctx, "fish", *failAt, *panics) })
+
+	// Shutdown cancels sd.Context, which makes Run stop the children; the
+	// component is done once Run has returned.
+	stopped := make(chan struct{})
+	sd.Register("supervisor", func(ctx context.Context) error {
+		select {
+		case <-stopped:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	if *runFor > 0 {
+		time.AfterFunc(*runFor, func() { sd.Shutdown(errors.New("time is up")) })
+	}
+
+	fmt.Printf("Supervising sheep and fish (%s), Ctrl-C to stop\n", st)
+	err := sup.Run(sd.Context())
+	close(stopped)
+	if err != nil {
+		err = errors.New(firstLine(err))
+		fmt.Println(err)
+	}
+	fmt.Print(sd.Shutdown(err))
+	if err == nil {
+		fmt.Println("All stopped")
+	}
+}
+
+// firstLine trims a panic's stack trace off an error for printing.
+func firstLine(err error) string {
+	s, _, _ := strings.Cut(err.Error(), "\n")
+	return s
+}
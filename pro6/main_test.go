@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"conc/shutdown"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// argsEnv, when set, makes the test binary run main with these
+// space-separated arguments instead of the tests.
+const argsEnv = "PRO6_MAIN_ARGS"
+
+func TestMain(m *testing.M) {
+	if args, ok := os.LookupEnv(argsEnv); ok {
+		os.Args = append([]string{os.Args[0]}, strings.Fields(args)...)
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runAndTerm starts main in a child process, sends it SIGTERM after a second
+// and returns its combined output and exit code.
+func runAndTerm(t *testing.T, args ...string) (string, int) {
+	t.Helper()
+	var out bytes.Buffer
+	child := exec.Command(os.Args[0], "-test.run=^$")
+	child.Env = append(os.Environ(), argsEnv+"="+strings.Join(args, " "))
+	child.Stdout, child.Stderr = &out, &out
+	if err := child.Start(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Second)
+	if err := child.Process.Signal(syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- child.Wait() }()
+	select {
+	case err := <-done:
+		var exit *exec.ExitError
+		if err != nil && !errors.As(err, &exit) {
+			t.Fatal(err)
+		}
+	case <-time.After(10 * time.Second):
+		child.Process.Kill()
+		<-done
+		t.Fatalf("child still running 10s after SIGTERM:\n%s", out.String())
+	}
+	return out.String(), child.ProcessState.ExitCode()
+}
+
+func TestSIGTERMDrains(t *testing.T) {
+	out, code := runAndTerm(t, "-fail-at", "0")
+	if code != 0 || !strings.Contains(out, "All stopped") || !strings.Contains(out, "terminated") {
+		t.Fatalf("clean run exited %d:\n%s", code, out)
+	}
+}
+
+func TestSIGTERMForcesStuckExit(t *testing.T) {
+	out, code := runAndTerm(t, "-fail-at", "0", "-stubborn", "-grace", "500ms")
+	if code != 1 || !strings.Contains(out, "supervisor") || !strings.Contains(out, shutdown.ErrStuck.Error()) {
+		t.Fatalf("stubborn run exited %d without the expected report:\n%s", code, out)
+	}
+}
@@ -1,7 +1,10 @@
 package main
 
 import (
+	"conc/shutdown"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -68,13 +71,17 @@ type Data struct {
 	MainField Main `json:"main"`
 }
 
-func fetchWeather(city string) (Data, error) {
+func fetchWeather(ctx context.Context, city string) (Data, error) {
 
 	data := Data{}
 
 	// https://openweathermap.org/current , https://home.openweathermap.org/api_keys
 	url := fmt.Sprintf("https://api.openweathermap.org/data/2.5/weather?q=%s&appid=%s", city, apiKey)
-	resp, err := http.Get(url)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return data, err
+	}
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		fmt.Printf("Error fetching weather for %s: %s\n", city, err)
 		return data, err
@@ -105,21 +112,44 @@ func fetchWeather(city string) (Data, error) {
 
 func main() {
 	startNow := time.Now()
+	// Ctrl-C cancels ctx, which aborts the requests still in flight; the
+	// fetchers then get up to 5s to return before the process is forced out.
+	sd := shutdown.New(shutdown.WithGrace(5 * time.Second))
+	ctx := sd.Context()
 	cities := []string{"toronto", "london", "paris", "tokyo", "beijing"}
 	// data := Data{}
 	wg := new(sync.WaitGroup)
 	wg.Add(len(cities))
+	mu := new(sync.Mutex)
 	justTest := []Data{}
 	for _, city := range cities {
 		// wg.Add(1)
 		go func(city string) {
 			defer wg.Done()
-			data, _ := fetchWeather(city)
+			data, _ := fetchWeather(ctx, city)
 			fmt.Printf("This is the temp %v from %s\n", data.MainField.Temp, city)
+			mu.Lock()
 			justTest = append(justTest, data)
+			mu.Unlock()
 		}(city)
 	}
+	sd.Register("fetchers", func(ctx context.Context) error {
+		done := make(chan struct{})
+		go func() {
+			wg.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
 	wg.Wait()
+	if rep := sd.Shutdown(nil); errors.Is(rep.Cause, shutdown.ErrSignal) {
+		fmt.Print(rep)
+	}
 
 	fmt.Println("This operation took: ", time.Since(startNow))
 }
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"conc/pubsub"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// broadcast is count with more than one listener: every subscriber of the
+// "sheep" topic gets its own copy of each message. The three subscribers show
+// the slow-consumer policies - "shepherd" keeps up, "dozy" reads slowly and
+// misses what doesn't fit in its buffer, "deaf" never reads and is cut off.
+func broadcast() {
+	hub := pubsub.New[string]()
+	ctx := context.Background()
+
+	shepherd, _ := hub.Subscribe("sheep")
+	dozy, _ := hub.Subscribe("sheep", pubsub.WithBuffer(1), pubsub.WithPolicy(pubsub.Drop))
+	deaf, _ := hub.Subscribe("sheep", pubsub.WithBuffer(1), pubsub.WithPolicy(pubsub.Disconnect))
+
+	var wg sync.WaitGroup
+	listen := func(name string, sub *pubsub.Subscription[string], pause time.Duration) {
+		defer wg.Done()
+		for msg := range sub.C {
+			fmt.Println(name, "heard", msg)
+			time.Sleep(pause)
+		}
+		fmt.Printf("%s done (dropped %d, err %v)\n", name, sub.Dropped(), sub.Err())
+	}
+	wg.Add(2)
+	go listen("shepherd", shepherd, 0)
+	go listen("dozy", dozy, time.Millisecond*250)
+
+	for i := 1; i <= 5; i++ {
+		n, _ := hub.Publish(ctx, "sheep", fmt.Sprint("sheep ", i))
+		fmt.Println("published to", n, "subscribers")
+		time.Sleep(time.Millisecond * 100)
+	}
+	hub.Close() // closes every subscriber's channel, exactly once
+
+	wg.Wait()
+	for range deaf.C {
+	}
+	fmt.Printf("deaf done (dropped %d, err %v)\n", deaf.Dropped(), deaf.Err())
+}
@@ -1,39 +1,55 @@
 package main
 
 import (
+	"conc/multiprod"
+	"context"
 	"fmt"
 	"time"
 )
 
 // must watch again https://www.youtube.com/watch?v=LvgVSSpwND8 !!
 
-func count(thing string, ch chan<-string) {
+// count sends thing five times as one of the channel's registered producers.
+// It never closes the channel, since sheep and fish share it; the channel
+// closes once the last producer calls Done.
+func count(thing string, p *multiprod.Producer[string]) {
+	defer p.Done()
 	for i := 1; i <= 5; i++ {
 		// fmt.Println(i, thing)
-		ch<- thing
+		if err := p.Send(context.Background(), thing); err != nil {
+			fmt.Println(err)
+			return
+		}
 		time.Sleep(time.Millisecond * 500)
 	}
 	// ch<- 0
-
-	close(ch)
 }
 
 func main() {
-	ch := make(chan string)
+	ch := multiprod.New[string](0)
+	sheep, _ := ch.Add()
+	fish, _ := ch.Add()
 
-	go count("sheep", ch)
-	// go count("fish", ch)
+	go count("sheep", sheep)
+	go count("fish", fish)
 	
 	// for {
-	// 	msg, open := <-ch
+	// 	msg, open := <-ch.C()
 	// 	if !open {
 	// 		break
 	// 	}
 	// 	fmt.Println(msg)
 	// }
 
-	for msg := range ch {
+	for msg := range ch.C() {
 		fmt.Println(msg)
 	}
+	// Too late: both producers are done and the channel is closed.
+	if err := sheep.Send(context.Background(), "sheep"); err != nil {
+		fmt.Println(err)
+	}
+
+	fmt.Println("------------------")
+	broadcast()
 
 }
\ No newline at end of file